@@ -1,11 +1,8 @@
 package main
 
 import (
-	"embed"
 	"errors"
 	"fmt"
-	"go/parser"
-	"go/token"
 	"io/fs"
 	"log"
 	"os"
@@ -13,13 +10,12 @@ import (
 	"regexp"
 	"runtime/debug"
 	"strings"
-	"text/template"
 
 	"github.com/spf13/pflag"
 	"go.uber.org/zap/buffer"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
 	"gopkg.in/yaml.v2"
-	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/openshift-knative/hack/pkg/project"
 	"github.com/openshift-knative/hack/pkg/prowgen"
@@ -29,12 +25,6 @@ const (
 	GenerateDockerfileOption = "dockerfile"
 )
 
-//go:embed Dockerfile.template
-var DockerfileTemplate embed.FS
-
-//go:embed BuildImageDockerfile.template
-var DockerfileBuildImageTemplate embed.FS
-
 func main() {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -53,6 +43,8 @@ func main() {
 		projectFilePath           string
 		dockerfileImageBuilderFmt string
 		registryImageFmt          string
+		templatesDir              string
+		tags                      []string
 	)
 
 	defaultIncludes := []string{
@@ -75,6 +67,8 @@ func main() {
 	pflag.StringVar(&projectFilePath, "project-file", filepath.Join(wd, "openshift", "project.yaml"), "Project metadata file path")
 	pflag.StringVar(&dockerfileImageBuilderFmt, "dockerfile-image-builder-fmt", "registry.ci.openshift.org/openshift/release:golang-%s", "Dockerfile image builder format")
 	pflag.StringVar(&registryImageFmt, "registry-image-fmt", "registry.ci.openshift.org/openshift/%s:%s", "Container registry image format")
+	pflag.StringVar(&templatesDir, "templates-dir", filepath.Join(wd, "openshift", "templates"), "Directory with *.tmpl templates overriding the embedded Dockerfile templates")
+	pflag.StringSliceVar(&tags, "tags", nil, "Build tags used to discover main packages, in addition to the project build tags")
 	pflag.Parse()
 
 	if rootDir == "" {
@@ -93,85 +87,53 @@ func main() {
 	includesRegex := toRegexp(includes)
 	excludesRegex := toRegexp(excludes)
 
-	mainPackagesPaths := sets.NewString()
-
-	err = filepath.Walk(rootDir, func(path string, info fs.FileInfo, err error) error {
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
-			return nil
-		}
-		path = filepath.Join(".", strings.TrimPrefix(path, rootDir))
-
-		include := true
-		if len(includesRegex) > 0 {
-			include = false
-			for _, r := range includesRegex {
-				if r.MatchString(path) {
-					include = true
-					break
-				}
-			}
-		}
-		for _, r := range excludesRegex {
-			if r.MatchString(path) {
-				include = false
-				break
-			}
-		}
-
-		if !include {
-			return nil
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("ReadFile %s failed: %w", path, err)
-		}
-		ast, err := parser.ParseFile(token.NewFileSet(), path, content, parser.PackageClauseOnly)
-		if err != nil {
-			return fmt.Errorf("ParseFile failed: %w", err)
+	metadata, err := project.ReadMetadataFile(projectFilePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Fatal("Failed to read project metadata file: ", err)
 		}
+		log.Println("File ", projectFilePath, " not found")
+		metadata = nil
+	}
 
-		if ast.Name.Name != "main" {
-			return nil
-		}
+	buildTags := tags
+	if metadata != nil {
+		buildTags = append(buildTags, metadata.Dockerfiles.Build.Tags...)
+	}
 
-		mainPackagesPaths.Insert(filepath.Dir(path))
-		return nil
-	})
+	mainPackages, err := discoverMainPackages(rootDir, includesRegex, excludesRegex, buildTags)
 	if err != nil {
 		log.Fatal(err, "\n", string(debug.Stack()))
 	}
 
-	for _, p := range mainPackagesPaths.List() {
-		log.Println("Main package path", p)
+	for _, p := range mainPackages {
+		log.Println("Main package path", p.Path)
 	}
 
 	if generators == GenerateDockerfileOption {
 		goMod := getGoMod(rootDir)
-		goVersion := goMod.Go.Version
+		// Builder images are tagged with the Go major and minor version, while the go directive
+		// can include the patch version, such as 1.22.0.
+		goVersion := strings.TrimPrefix(semver.MajorMinor("v"+goMod.Go.Version), "v")
 
 		builderImage := fmt.Sprintf(dockerfileImageBuilderFmt, goVersion)
 
 		goPackageToImageMapping := map[string]string{}
 
-		metadata, err := project.ReadMetadataFile(projectFilePath)
+		templates, err := newTemplates(templatesDir)
 		if err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				log.Fatal("Failed to read project metadata file: ", err)
-			}
-			log.Println("File ", projectFilePath, " not found")
-			metadata = nil
+			log.Fatal("Failed creating templates ", err)
 		}
 
-		bt, err := template.ParseFS(DockerfileBuildImageTemplate, "*.template")
+		bd, err := newBuildImageData(metadata)
 		if err != nil {
-			log.Fatal("Failed creating template ", err)
-		}
-		d := map[string]interface{}{
-			"builder": builderImage,
+			log.Fatal(err)
 		}
+		bd.Builder = builderImage
+		bd.GoVersion = goVersion
+		bd.ModulePath = goMod.Module.Mod.Path
 		bf := &buffer.Buffer{}
-		if err := bt.Execute(bf, d); err != nil {
+		if err := templates.buildImage().Execute(bf, bd); err != nil {
 			log.Fatal("Failed to execute template", err)
 		}
 
@@ -187,40 +149,34 @@ func main() {
 			log.Fatal("Failed writing file", err)
 		}
 
-		for _, p := range mainPackagesPaths.List() {
-			d := map[string]interface{}{
-				"main":    p,
-				"builder": builderImage,
-			}
-
-			t, err := template.ParseFS(DockerfileTemplate, "*.template")
-			if err != nil {
-				log.Fatal("Failed creating template ", err)
-			}
-
-			bf := &buffer.Buffer{}
-			if err := t.Execute(bf, d); err != nil {
-				log.Fatal("Failed to execute template", err)
-			}
+		imageNames, err := mainPackageImageNames(metadata, mainPackages)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-			out := filepath.Join(output, dockerfilesDir, filepath.Base(p))
+		for _, mainPackage := range mainPackages {
+			p := mainPackage.Path
+			out := filepath.Join(output, dockerfilesDir, imageNames[p])
 			context := prowgen.ProductionContext
-			if strings.Contains(p, "test") {
+			if isTestMainPackage(p) {
 				context = prowgen.TestContext
-				out = filepath.Join(output, dockerfilesTestDir, filepath.Base(p))
+				out = filepath.Join(output, dockerfilesTestDir, imageNames[p])
 			}
+			dockerfilePath := filepath.Join(out, "Dockerfile")
 
-			if err := os.RemoveAll(out); err != nil && !errors.Is(err, os.ErrNotExist) {
-				log.Fatal(err)
-			}
-			if err := os.MkdirAll(out, fs.ModePerm); err != nil && !errors.Is(err, fs.ErrExist) {
+			goPackage := mainPackage.ImportPath
+
+			d, err := newDockerfileData(metadata, mainPackage)
+			if err != nil {
 				log.Fatal(err)
 			}
-			dockerfilePath := filepath.Join(out, "Dockerfile")
-			if err := os.WriteFile(dockerfilePath, bf.Bytes(), fs.ModePerm); err != nil {
-				log.Fatal("Failed writing file", err)
-			}
+			d.Builder = builderImage
+			d.GoVersion = goVersion
+			d.ModulePath = goMod.Module.Mod.Path
+			d.Package = goPackage
+			d.Context = string(context)
 
+			var image string
 			if metadata != nil {
 				v, err := prowgen.ProjectDirectoryImageBuildStepConfigurationFuncFromImageInput(
 					prowgen.Repository{
@@ -234,15 +190,39 @@ func main() {
 				if err != nil {
 					log.Fatal("Failed to derive image name ", err)
 				}
-				image := fmt.Sprintf(registryImageFmt, v.To, metadata.Project.Tag)
+				d.ImageName = string(v.To)
+				image = fmt.Sprintf(registryImageFmt, v.To, metadata.Project.Tag)
 				if imageEnv := os.Getenv(strings.ToUpper(strings.ReplaceAll(string(v.To), "-", "_"))); imageEnv != "" {
 					image = imageEnv
 				}
-				if strings.HasPrefix(p, "vendor/") {
-					goPackageToImageMapping[strings.Replace(p, "vendor/", "", 1)] = image
-				} else {
-					goPackageToImageMapping[filepath.Join(goMod.Module.Mod.Path, p)] = image
-				}
+			}
+
+			templateName, err := metadata.TemplateFor(p)
+			if err != nil {
+				log.Fatal("Failed to select template ", err)
+			}
+			t, err := templates.dockerfile(templateName)
+			if err != nil {
+				log.Fatal("Failed to select template for ", p, ": ", err)
+			}
+
+			bf := &buffer.Buffer{}
+			if err := t.Execute(bf, d); err != nil {
+				log.Fatal("Failed to execute template", err)
+			}
+
+			if err := os.RemoveAll(out); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Fatal(err)
+			}
+			if err := os.MkdirAll(out, fs.ModePerm); err != nil && !errors.Is(err, fs.ErrExist) {
+				log.Fatal(err)
+			}
+			if err := os.WriteFile(dockerfilePath, bf.Bytes(), fs.ModePerm); err != nil {
+				log.Fatal("Failed writing file", err)
+			}
+
+			if metadata != nil {
+				goPackageToImageMapping[goPackage] = image
 			}
 		}
 
@@ -260,6 +240,50 @@ func main() {
 	}
 }
 
+// mainPackageImageNames returns the image names, without prefix and context, of the main packages
+// by path, which are the names of the directories of their Dockerfiles.
+//
+// Main packages with the same image name in the same context would overwrite each other's
+// Dockerfile and image, so they are reported as collisions.
+func mainPackageImageNames(metadata *project.Metadata, mainPackages []mainPackage) (map[string]string, error) {
+	prefix := ""
+	if metadata != nil {
+		prefix = metadata.Project.ImagePrefix
+	}
+	names := make(map[string]string, len(mainPackages))
+	sources := make(map[string][]string, len(mainPackages))
+	for _, mainPackage := range mainPackages {
+		p := mainPackage.Path
+		name, scheme, err := metadata.ImageNameFor(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive image name of %s: %w", p, err)
+		}
+		if name == "" {
+			name, err = prowgen.DefaultNamingPolicy.MainPackageImageName(prowgen.ImageNamingScheme(scheme), p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to derive image name of %s: %w", p, err)
+			}
+		}
+		names[p] = name
+
+		context := prowgen.ProductionContext
+		if isTestMainPackage(p) {
+			context = prowgen.TestContext
+		}
+		image := prowgen.DefaultNamingPolicy.ImageName(prefix, context, name)
+		sources[image] = append(sources[image], p)
+	}
+	if err := prowgen.ImageNameCollisions(sources); err != nil {
+		return nil, fmt.Errorf("%w\nuse the path imageNaming or set imageName overrides in the project file", err)
+	}
+	return names, nil
+}
+
+// isTestMainPackage returns true when the image of the given main package path is a test image.
+func isTestMainPackage(p string) bool {
+	return strings.Contains(p, "test")
+}
+
 func getGoMod(rootDir string) *modfile.File {
 	goModFile := filepath.Join(rootDir, "go.mod")
 	goModContent, err := os.ReadFile(goModFile)
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// mainPackage is a main package found in the root directory.
+type mainPackage struct {
+	// Path is the package directory relative to the root directory, such as cmd/webhook or
+	// vendor/knative.dev/reconciler-test/cmd/eventshub.
+	Path string
+	// ImportPath is the package import path.
+	ImportPath string
+	// ModuleDir is the directory of the nested module containing the package relative to the root
+	// directory, it is empty for the packages of the root module and vendored packages.
+	ModuleDir string
+}
+
+// discoverMainPackages returns the main packages in the directories containing Go files matching
+// the includes and not matching the excludes, sorted by path.
+//
+// Packages are loaded with go/packages from the closest module, so that build constraints, such as
+// the given build tags and `//go:build ignore` files, go.work files and nested modules are
+// respected. Directories ignored by the go command, such as testdata, are skipped.
+//
+// It returns an error listing the main packages failing to load, such as a directory mixing
+// package main and other packages.
+func discoverMainPackages(rootDir string, includes, excludes []*regexp.Regexp, tags []string) ([]mainPackage, error) {
+	candidates, err := candidatePackageDirs(rootDir, includes, excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group candidates by module root and by whether they are vendored, vendored packages are
+	// loaded by import path in vendor mode.
+	type loadKey struct {
+		moduleRoot string
+		vendor     bool
+	}
+	patterns := make(map[loadKey][]string)
+	for _, dir := range candidates {
+		moduleRoot := closestModuleRoot(rootDir, dir)
+		rel, err := filepath.Rel(moduleRoot, filepath.Join(rootDir, dir))
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, "vendor/") {
+			k := loadKey{moduleRoot: moduleRoot, vendor: true}
+			patterns[k] = append(patterns[k], strings.TrimPrefix(rel, "vendor/"))
+			continue
+		}
+		k := loadKey{moduleRoot: moduleRoot}
+		patterns[k] = append(patterns[k], "./"+rel)
+	}
+
+	var mains []mainPackage
+	var loadErrors []string
+	for k, ps := range patterns {
+		var buildFlags []string
+		if len(tags) > 0 {
+			buildFlags = append(buildFlags, "-tags="+strings.Join(tags, ","))
+		}
+		if k.vendor {
+			buildFlags = append(buildFlags, "-mod=vendor")
+		}
+		pkgs, err := packages.Load(&packages.Config{
+			Mode:       packages.NeedName | packages.NeedFiles,
+			Dir:        k.moduleRoot,
+			BuildFlags: buildFlags,
+		}, ps...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load packages in %s: %w", k.moduleRoot, err)
+		}
+		for _, pkg := range pkgs {
+			if len(pkg.Errors) > 0 {
+				// Packages whose files are all excluded by build constraints, such as
+				// `//go:build ignore` programs, aren't built.
+				if len(pkg.GoFiles) == 0 || !hasMainFile(pkg.GoFiles) {
+					log.Println("Skipping package", pkg.PkgPath, pkg.Errors)
+					continue
+				}
+				for _, e := range pkg.Errors {
+					loadErrors = append(loadErrors, fmt.Sprintf("%s: %s", pkg.PkgPath, e.Msg))
+				}
+				continue
+			}
+			if pkg.Name != "main" || len(pkg.GoFiles) == 0 {
+				continue
+			}
+			path, err := filepath.Rel(rootDir, filepath.Dir(pkg.GoFiles[0]))
+			if err != nil {
+				return nil, err
+			}
+			m := mainPackage{Path: filepath.ToSlash(path), ImportPath: pkg.PkgPath}
+			if moduleDir, err := filepath.Rel(rootDir, k.moduleRoot); err == nil && moduleDir != "." && !k.vendor {
+				m.ModuleDir = filepath.ToSlash(moduleDir)
+			}
+			mains = append(mains, m)
+		}
+	}
+	if len(loadErrors) > 0 {
+		sort.Strings(loadErrors)
+		return nil, fmt.Errorf("failed to load main packages:\n%s", strings.Join(loadErrors, "\n"))
+	}
+	sort.Slice(mains, func(i, j int) bool {
+		return mains[i].Path < mains[j].Path
+	})
+	return mains, nil
+}
+
+// hasMainFile returns true when any of the given files declares package main.
+func hasMainFile(files []string) bool {
+	for _, f := range files {
+		file, err := parser.ParseFile(token.NewFileSet(), f, nil, parser.PackageClauseOnly)
+		if err == nil && file.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// candidatePackageDirs returns the directories, relative to the root directory, containing Go files
+// matching the includes and not matching the excludes.
+func candidatePackageDirs(rootDir string, includes, excludes []*regexp.Regexp) ([]string, error) {
+	dirs := make(map[string]bool)
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				log.Println("Skipping unreadable path", path, err)
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != rootDir && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		if matchesPath(rel, includes, excludes) {
+			dirs[filepath.Dir(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", rootDir, err)
+	}
+
+	candidates := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		candidates = append(candidates, dir)
+	}
+	sort.Strings(candidates)
+	return candidates, nil
+}
+
+func matchesPath(path string, includes, excludes []*regexp.Regexp) bool {
+	include := true
+	if len(includes) > 0 {
+		include = false
+		for _, r := range includes {
+			if r.MatchString(path) {
+				include = true
+				break
+			}
+		}
+	}
+	for _, r := range excludes {
+		if r.MatchString(path) {
+			return false
+		}
+	}
+	return include
+}
+
+// closestModuleRoot returns the closest directory containing a go.mod file from the given
+// directory, relative to the root directory, up to the root directory.
+func closestModuleRoot(rootDir, dir string) string {
+	// Vendored packages belong to the module vendoring them.
+	if i := strings.Index(filepath.ToSlash(dir)+"/", "vendor/"); i >= 0 && (i == 0 || filepath.ToSlash(dir)[i-1] == '/') {
+		dir = dir[:i]
+	}
+	for d := filepath.Join(rootDir, dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		if d == rootDir || !strings.HasPrefix(d, rootDir) {
+			return rootDir
+		}
+	}
+}
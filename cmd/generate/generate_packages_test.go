@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiscoverMainPackages(t *testing.T) {
+	rootDir := t.TempDir()
+	writeFiles(t, rootDir, map[string]string{
+		"go.mod": "module example.com/project\n\ngo 1.18\n\nrequire example.com/dep v1.0.0\n",
+		"vendor/modules.txt": "# example.com/dep v1.0.0\n" +
+			"## explicit\n" +
+			"example.com/dep/cmd/eventshub\n",
+		"vendor/example.com/dep/cmd/eventshub/main.go": "package main\n\nfunc main() {}\n",
+
+		"cmd/webhook/main.go":      "package main\n\nfunc main() {}\n",
+		"cmd/webhook/main_test.go": "package main\n",
+		// Programs run with go run, excluded by build constraints.
+		"cmd/webhook/gen.go":  "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"cmd/ignored/main.go": "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"cmd/tagged/main.go":  "//go:build tagged\n\npackage main\n\nfunc main() {}\n",
+		"pkg/lib/lib.go":      "package lib\n",
+
+		"nested/go.mod":           "module example.com/nested\n\ngo 1.18\n",
+		"nested/cmd/tool/main.go": "package main\n\nfunc main() {}\n",
+
+		// Directories ignored by the go command.
+		"testdata/cmd/fixture/main.go": "package main\n\nfunc main() {}\n",
+		"_tools/cmd/tool/main.go":      "package main\n\nfunc main() {}\n",
+		".github/cmd/tool/main.go":     "package main\n\nfunc main() {}\n",
+	})
+
+	tests := []struct {
+		name     string
+		includes []string
+		excludes []string
+		tags     []string
+		want     []mainPackage
+	}{
+		{
+			name: "without tags",
+			want: []mainPackage{
+				{Path: "cmd/webhook", ImportPath: "example.com/project/cmd/webhook"},
+				{Path: "nested/cmd/tool", ImportPath: "example.com/nested/cmd/tool", ModuleDir: "nested"},
+				{Path: "vendor/example.com/dep/cmd/eventshub", ImportPath: "example.com/dep/cmd/eventshub"},
+			},
+		},
+		{
+			name: "with tags",
+			tags: []string{"tagged"},
+			want: []mainPackage{
+				{Path: "cmd/tagged", ImportPath: "example.com/project/cmd/tagged"},
+				{Path: "cmd/webhook", ImportPath: "example.com/project/cmd/webhook"},
+				{Path: "nested/cmd/tool", ImportPath: "example.com/nested/cmd/tool", ModuleDir: "nested"},
+				{Path: "vendor/example.com/dep/cmd/eventshub", ImportPath: "example.com/dep/cmd/eventshub"},
+			},
+		},
+		{
+			name:     "excludes",
+			excludes: []string{".*vendor.*", "nested.*"},
+			want: []mainPackage{
+				{Path: "cmd/webhook", ImportPath: "example.com/project/cmd/webhook"},
+			},
+		},
+		{
+			name:     "includes",
+			includes: []string{"nested/.*"},
+			want: []mainPackage{
+				{Path: "nested/cmd/tool", ImportPath: "example.com/nested/cmd/tool", ModuleDir: "nested"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discoverMainPackages(rootDir, toRegexp(tt.includes), toRegexp(tt.excludes), tt.tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected main packages (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoverMainPackagesLoadErrors(t *testing.T) {
+	rootDir := t.TempDir()
+	writeFiles(t, rootDir, map[string]string{
+		"go.mod":              "module example.com/project\n\ngo 1.18\n",
+		"cmd/webhook/main.go": "package main\n\nfunc main() {}\n",
+		// A main package failing to load must not be silently skipped.
+		"cmd/mixed/main.go":  "package main\n\nfunc main() {}\n",
+		"cmd/mixed/other.go": "package other\n",
+		// Libraries failing to load aren't built.
+		"pkg/mixed/a.go": "package a\n",
+		"pkg/mixed/b.go": "package b\n",
+	})
+
+	_, err := discoverMainPackages(rootDir, nil, nil, nil)
+	if err == nil {
+		t.Fatal("expected error loading cmd/mixed")
+	}
+	if !strings.Contains(err.Error(), "example.com/project/cmd/mixed") {
+		t.Errorf("expected error for example.com/project/cmd/mixed, got %v", err)
+	}
+	if strings.Contains(err.Error(), "example.com/project/pkg/mixed") {
+		t.Errorf("unexpected error for example.com/project/pkg/mixed, got %v", err)
+	}
+
+	// Excluding the failing main package fixes the discovery.
+	got, err := discoverMainPackages(rootDir, nil, []*regexp.Regexp{regexp.MustCompile("cmd/mixed")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mainPackage{{Path: "cmd/webhook", ImportPath: "example.com/project/cmd/webhook"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected main packages (-want, +got): \n%s", diff)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift-knative/hack/pkg/project"
+)
+
+const (
+	// DockerfileTemplateName is the name of the template overriding the embedded Dockerfile template
+	// of every main package.
+	DockerfileTemplateName = "Dockerfile.tmpl"
+	// BuildImageDockerfileTemplateName is the name of the template overriding the embedded build
+	// image Dockerfile template.
+	BuildImageDockerfileTemplateName = "BuildImageDockerfile.tmpl"
+)
+
+//go:embed Dockerfile.template
+var DockerfileTemplate embed.FS
+
+//go:embed BuildImageDockerfile.template
+var DockerfileBuildImageTemplate embed.FS
+
+// BuildImageData is the data of the build image Dockerfile template.
+type BuildImageData struct {
+	// Builder is the builder image.
+	Builder    string
+	GoVersion  string
+	ModulePath string
+	// Metadata is the project metadata, nil when there is no project file.
+	Metadata *project.Metadata
+	// BuildImage is the build image configuration.
+	BuildImage project.BuildImage
+	// Install are the Dockerfile instructions installing the build image tools.
+	Install []string
+}
+
+func newBuildImageData(metadata *project.Metadata) (*BuildImageData, error) {
+	buildImage, err := metadata.BuildImageConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure build image: %w", err)
+	}
+	install, err := buildImage.Instructions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure build image: %w", err)
+	}
+	return &BuildImageData{
+		Metadata:   metadata,
+		BuildImage: buildImage,
+		Install:    install,
+	}, nil
+}
+
+// DockerfileData is the data of the Dockerfile template of a main package.
+type DockerfileData struct {
+	BuildImageData
+
+	// Main is the main package path relative to the root directory, such as cmd/webhook.
+	Main string
+	// Package is the main package import path.
+	Package string
+	// ImageName is the name of the image in the CI configuration, such as knative-eventing-webhook,
+	// it is empty when there is no project file.
+	ImageName string
+	// Context is the image context, test for test images and empty for production images.
+	Context string
+	// Version is the project tag.
+	Version string
+
+	Runtime project.Runtime
+	Build   project.Build
+	// GoBuild is the command building the main package to /usr/bin/main, from the directory of
+	// its module.
+	GoBuild string
+	// Entrypoint is the runtime entrypoint in JSON form.
+	Entrypoint string
+	// Packages is the space separated list of runtime packages.
+	Packages string
+	// Labels are the runtime labels as sorted key="value" pairs.
+	Labels []string
+}
+
+func newDockerfileData(metadata *project.Metadata, pkg mainPackage) (*DockerfileData, error) {
+	mainPackage := pkg.Path
+	runtimeConfig, err := metadata.RuntimeFor(mainPackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure runtime image: %w", err)
+	}
+	build, err := metadata.BuildFor(mainPackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure build: %w", err)
+	}
+	var goBuild string
+	if pkg.ModuleDir != "" {
+		goBuild, err = build.GoBuild(strings.TrimPrefix(mainPackage, pkg.ModuleDir+"/"), "/usr/bin/main")
+		goBuild = fmt.Sprintf("(cd %s && %s)", pkg.ModuleDir, goBuild)
+	} else {
+		goBuild, err = build.GoBuild(mainPackage, "/usr/bin/main")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure build: %w", err)
+	}
+	entrypoint, err := json.Marshal(runtimeConfig.Entrypoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entrypoint: %w", err)
+	}
+	d := &DockerfileData{
+		BuildImageData: BuildImageData{Metadata: metadata},
+		Main:           mainPackage,
+		Runtime:        runtimeConfig,
+		Build:          build,
+		GoBuild:        goBuild,
+		Entrypoint:     string(entrypoint),
+		Packages:       strings.Join(runtimeConfig.Packages, " "),
+		Labels:         dockerfileLabels(runtimeConfig.Labels),
+	}
+	if metadata != nil {
+		d.Version = metadata.Project.Tag
+	}
+	return d, nil
+}
+
+// dockerfileLabels returns the labels as sorted key="value" pairs.
+func dockerfileLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sets.StringKeySet(labels).List() {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, strconv.Quote(labels[k])))
+	}
+	return pairs
+}
+
+// templates holds the embedded templates and the project templates overriding them.
+type templates struct {
+	dir               string
+	overrides         *template.Template
+	defaultDockerfile *template.Template
+	defaultBuildImage *template.Template
+}
+
+// newTemplates parses the embedded templates and the *.tmpl templates in the given directory,
+// if it exists.
+//
+// Project templates can define and use each other's named templates.
+func newTemplates(dir string) (*templates, error) {
+	t := &templates{dir: dir}
+
+	var err error
+	t.defaultDockerfile, err = template.ParseFS(DockerfileTemplate, "Dockerfile.template")
+	if err != nil {
+		return nil, err
+	}
+	t.defaultBuildImage, err = template.ParseFS(DockerfileBuildImageTemplate, "BuildImageDockerfile.template")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return t, nil
+		}
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return t, nil
+	}
+	t.overrides, err = template.ParseFiles(matches...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates in %s: %w", dir, err)
+	}
+	return t, nil
+}
+
+// dockerfile returns the template of a main package: the named project template when name is set,
+// otherwise the DockerfileTemplateName project template, if any, otherwise the embedded template.
+func (t *templates) dockerfile(name string) (*template.Template, error) {
+	if name != "" {
+		if o := t.override(name); o != nil {
+			return o, nil
+		}
+		return nil, fmt.Errorf("template %q not found in %s", name, t.dir)
+	}
+	if o := t.override(DockerfileTemplateName); o != nil {
+		return o, nil
+	}
+	return t.defaultDockerfile, nil
+}
+
+// buildImage returns the BuildImageDockerfileTemplateName project template, if any, otherwise the
+// embedded template.
+func (t *templates) buildImage() *template.Template {
+	if o := t.override(BuildImageDockerfileTemplateName); o != nil {
+		return o
+	}
+	return t.defaultBuildImage
+}
+
+func (t *templates) override(name string) *template.Template {
+	if t.overrides == nil {
+		return nil
+	}
+	return t.overrides.Lookup(name)
+}
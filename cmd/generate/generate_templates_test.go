@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift-knative/hack/pkg/project"
+)
+
+func TestTemplatesDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "Dockerfile-global.tmpl", `global {{.Main}}`)
+	writeTemplate(t, dir, "Dockerfile-webhook.tmpl", `webhook {{.Main}}`)
+
+	metadata := &project.Metadata{Dockerfiles: project.Dockerfiles{
+		Template: "Dockerfile-global.tmpl",
+		Overrides: []project.DockerfileOverride{
+			{Match: "cmd/webhook", Template: "Dockerfile-webhook.tmpl"},
+			{Match: "cmd/missing", Template: "Dockerfile-missing.tmpl"},
+		},
+	}}
+
+	tmpls, err := newTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mainPackage string
+		want        string
+		wantErr     string
+	}{
+		{
+			mainPackage: "cmd/controller",
+			want:        "global cmd/controller",
+		},
+		{
+			// The override template wins over dockerfiles.template.
+			mainPackage: "cmd/webhook",
+			want:        "webhook cmd/webhook",
+		},
+		{
+			mainPackage: "cmd/missing",
+			wantErr:     `template "Dockerfile-missing.tmpl" not found in ` + dir,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mainPackage, func(t *testing.T) {
+			name, err := metadata.TemplateFor(tt.mainPackage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := tmpls.dockerfile(name)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("want error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := &bytes.Buffer{}
+			if err := tmpl.Execute(out, DockerfileData{Main: tt.mainPackage}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("Unexpected Dockerfile (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTemplatesDefaults(t *testing.T) {
+	// Without templates directory, the embedded templates are used.
+	tmpls, err := newTemplates(filepath.Join(t.TempDir(), "templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := tmpls.dockerfile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl != tmpls.defaultDockerfile {
+		t.Error("expected the embedded Dockerfile template")
+	}
+	if tmpls.buildImage() != tmpls.defaultBuildImage {
+		t.Error("expected the embedded build image Dockerfile template")
+	}
+	if _, err := tmpls.dockerfile("Dockerfile-webhook.tmpl"); err == nil || !strings.Contains(err.Error(), `template "Dockerfile-webhook.tmpl" not found`) {
+		t.Errorf("expected template not found error, got %v", err)
+	}
+
+	// Project templates named after the embedded ones override them.
+	dir := t.TempDir()
+	writeTemplate(t, dir, DockerfileTemplateName, `project {{.Main}}`)
+	writeTemplate(t, dir, BuildImageDockerfileTemplateName, `project build image`)
+	tmpls, err = newTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err = tmpls.dockerfile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name() != DockerfileTemplateName {
+		t.Errorf("want template %s, got %s", DockerfileTemplateName, tmpl.Name())
+	}
+	if name := tmpls.buildImage().Name(); name != BuildImageDockerfileTemplateName {
+		t.Errorf("want template %s, got %s", BuildImageDockerfileTemplateName, name)
+	}
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
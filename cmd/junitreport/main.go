@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/openshift-knative/hack/pkg/junitreport"
+)
+
+func main() {
+	junitreport.Main()
+}
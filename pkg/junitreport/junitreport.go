@@ -0,0 +1,187 @@
+package junitreport
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/release/tools/junitreport/pkg/api"
+)
+
+// StepProperty is the test suite property recording the CI step that produced the test suite.
+const StepProperty = "step"
+
+// Main merges the JUnit results in the artifacts directory of a job, prints a summary and,
+// optionally, writes the merged results.
+//
+// `junitreport flakes` computes flaky-test statistics across multiple runs instead.
+func Main() {
+	if len(os.Args) > 1 && os.Args[1] == "flakes" {
+		os.Exit(FlakesMain(os.Args[2:]))
+	}
+
+	artifactDir := flag.String("artifact-dir", os.Getenv("ARTIFACT_DIR"), "Artifacts directory of the job, it defaults to ${ARTIFACT_DIR}")
+	output := flag.String("output", "", "Write the merged JUnit results to the given file, - for stdout")
+	failOnFailures := flag.Bool("fail-on-failures", false, "Exit with code 1 when a test failed")
+	flag.Parse()
+
+	if *artifactDir == "" {
+		log.Fatalln("Artifacts directory is required, use -artifact-dir or ARTIFACT_DIR")
+	}
+
+	suites, err := Merge(*artifactDir, *output)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *output != "" {
+		if err := Write(suites, *output); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	summary := Summarize(suites)
+	if *output == "-" {
+		log.Print(summary)
+	} else {
+		fmt.Print(summary)
+	}
+
+	if *failOnFailures && summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// Merge reads the JUnit XML files in the artifacts directory of a job, such as the results of the
+// test step and the must-gather steps, and merges them.
+//
+// Each test suite records the step that produced it, which is the first directory of the file
+// relative to the artifacts directory, in the StepProperty property. Files that aren't JUnit
+// results and the excluded files are skipped.
+func Merge(dir string, excludes ...string) (*api.TestSuites, error) {
+	excluded := make(map[string]bool, len(excludes))
+	for _, e := range excludes {
+		if abs, err := filepath.Abs(e); err == nil {
+			excluded[abs] = true
+		}
+	}
+
+	merged := &api.TestSuites{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				log.Println("Skipping unreadable path", path, err)
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".xml" {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && excluded[abs] {
+			return nil
+		}
+		suites, err := ReadFile(path)
+		if err != nil {
+			log.Println("Skipping", err)
+			return nil
+		}
+		step := stepOf(dir, path)
+		for _, suite := range suites {
+			if step != "" {
+				suite.AddProperty(StepProperty, step)
+			}
+			merged.Suites = append(merged.Suites, suite)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge JUnit results in %s: %w", dir, err)
+	}
+
+	sort.SliceStable(merged.Suites, func(i, j int) bool {
+		si, sj := Step(merged.Suites[i]), Step(merged.Suites[j])
+		if si != sj {
+			return si < sj
+		}
+		return merged.Suites[i].Name < merged.Suites[j].Name
+	})
+	return merged, nil
+}
+
+// ReadFile reads a JUnit file whose root is either testsuites or testsuite.
+func ReadFile(path string) ([]*api.TestSuite, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suites := &api.TestSuites{}
+	if err := xml.Unmarshal(content, suites); err == nil {
+		return suites.Suites, nil
+	}
+	suite := &api.TestSuite{}
+	if err := xml.Unmarshal(content, suite); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit file %s: %w", path, err)
+	}
+	return []*api.TestSuite{suite}, nil
+}
+
+// Write writes the JUnit results to the given file, - for stdout.
+func Write(suites *api.TestSuites, path string) error {
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit results: %w", err)
+	}
+	out = append([]byte(xml.Header), append(out, '\n')...)
+	if path == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write JUnit results %s: %w", path, err)
+	}
+	return nil
+}
+
+// Step returns the step that produced the test suite, if known.
+func Step(suite *api.TestSuite) string {
+	for _, p := range suite.Properties {
+		if p.Name == StepProperty {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func stepOf(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// walkTestCases calls f for every test case of the suite and its children with the name of the
+// suite containing it.
+func walkTestCases(suite *api.TestSuite, f func(suite string, tc *api.TestCase)) {
+	for _, tc := range suite.TestCases {
+		f(suite.Name, tc)
+	}
+	for _, child := range suite.Children {
+		walkTestCases(child, f)
+	}
+}
@@ -0,0 +1,235 @@
+package junitreport
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/release/tools/junitreport/pkg/api"
+)
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// TestStatistics describes the results of a test across multiple runs.
+type TestStatistics struct {
+	Suite string `json:"suite"`
+	Test  string `json:"test"`
+	// Runs is the number of runs executing the test.
+	Runs    int `json:"runs"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	// FailureRate is the ratio of failures among the runs that passed or failed.
+	FailureRate float64 `json:"failureRate"`
+	// Flaky is true when the test both passed and failed, in different runs or in the same run
+	// after retries.
+	Flaky bool `json:"flaky"`
+	// AverageDuration is the average duration in seconds of the runs that passed or failed, the
+	// duration of a run being the duration of its final attempt when the test has been retried.
+	AverageDuration float64 `json:"averageDuration"`
+}
+
+// FlakesReport reports flaky and failing tests across multiple runs.
+type FlakesReport struct {
+	Runs  int              `json:"runs"`
+	Tests []TestStatistics `json:"tests"`
+}
+
+// FlakesMain runs `junitreport flakes` with the given arguments and returns the exit code.
+func FlakesMain(args []string) int {
+	flags := flag.NewFlagSet("flakes", flag.ExitOnError)
+	history := flags.String("history", "", "Directory with the results of multiple runs, each subdirectory or JUnit file being a run")
+	format := flags.String("format", TextFormat, "Format of the report: text or json")
+	minRuns := flags.Int("min-runs", 1, "Minimum number of runs executing a test to report it")
+	all := flags.Bool("all", false, "Report every test instead of only flaky and failing tests")
+	_ = flags.Parse(args)
+
+	if *history == "" {
+		log.Println("History directory is required, use -history")
+		return 2
+	}
+
+	report, err := Flakes(*history)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+	report.Filter(*minRuns, *all)
+	if err := report.Write(os.Stdout, *format); err != nil {
+		log.Println(err)
+		return 2
+	}
+	return 0
+}
+
+// Flakes computes the statistics of tests across the runs stored in the history directory.
+//
+// Each subdirectory of the history directory is a run, such as the artifacts directory of a job,
+// and so is each JUnit file at its root.
+func Flakes(history string) (*FlakesReport, error) {
+	entries, err := os.ReadDir(history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory %s: %w", history, err)
+	}
+
+	report := &FlakesReport{}
+	stats := make(map[string]*TestStatistics)
+	durations := make(map[string]float64)
+	for _, e := range entries {
+		path := filepath.Join(history, e.Name())
+		var suites []*api.TestSuite
+		if e.IsDir() {
+			merged, err := Merge(path)
+			if err != nil {
+				return nil, err
+			}
+			suites = merged.Suites
+		} else if filepath.Ext(path) == ".xml" {
+			suites, err = ReadFile(path)
+			if err != nil {
+				log.Println("Skipping", err)
+				continue
+			}
+		} else {
+			continue
+		}
+		if len(suites) == 0 {
+			continue
+		}
+		report.Runs++
+
+		// Retried tests appear multiple times in the same run, the last executed attempt is the
+		// final one.
+		type result struct {
+			passed, failed, skipped bool
+			duration                float64
+		}
+		results := make(map[string]*result)
+		for _, suite := range suites {
+			walkTestCases(suite, func(suiteName string, tc *api.TestCase) {
+				k := suiteName + "\x00" + tc.Name
+				if _, ok := stats[k]; !ok {
+					stats[k] = &TestStatistics{Suite: suiteName, Test: tc.Name}
+				}
+				r, ok := results[k]
+				if !ok {
+					r = &result{}
+					results[k] = r
+				}
+				switch {
+				case tc.SkipMessage != nil:
+					r.skipped = true
+				case tc.FailureOutput != nil:
+					r.failed = true
+					r.duration = tc.Duration
+				default:
+					r.passed = true
+					r.duration = tc.Duration
+				}
+			})
+		}
+		for k, r := range results {
+			s := stats[k]
+			s.Runs++
+			if r.passed && r.failed {
+				s.Flaky = true
+			}
+			if r.passed || r.failed {
+				durations[k] += r.duration
+			}
+			switch {
+			case r.failed:
+				s.Failed++
+			case r.passed:
+				s.Passed++
+			default:
+				s.Skipped++
+			}
+		}
+	}
+
+	for k, s := range stats {
+		if s.Passed > 0 && s.Failed > 0 {
+			s.Flaky = true
+		}
+		if executed := s.Passed + s.Failed; executed > 0 {
+			s.FailureRate = float64(s.Failed) / float64(executed)
+			s.AverageDuration = durations[k] / float64(executed)
+		}
+		report.Tests = append(report.Tests, *s)
+	}
+	sort.Slice(report.Tests, func(i, j int) bool {
+		ti, tj := report.Tests[i], report.Tests[j]
+		if ti.Flaky != tj.Flaky {
+			return ti.Flaky
+		}
+		if ti.FailureRate != tj.FailureRate {
+			return ti.FailureRate > tj.FailureRate
+		}
+		if ti.Suite != tj.Suite {
+			return ti.Suite < tj.Suite
+		}
+		return ti.Test < tj.Test
+	})
+	return report, nil
+}
+
+// Filter keeps the tests executed in at least minRuns runs and, unless all is true, only the
+// flaky and failing tests.
+func (r *FlakesReport) Filter(minRuns int, all bool) {
+	tests := r.Tests[:0]
+	for _, t := range r.Tests {
+		if t.Runs < minRuns {
+			continue
+		}
+		if !all && !t.Flaky && t.Failed == 0 {
+			continue
+		}
+		tests = append(tests, t)
+	}
+	r.Tests = tests
+}
+
+// Write writes the report in the given format: text or json.
+func (r *FlakesReport) Write(w io.Writer, format string) error {
+	switch format {
+	case JSONFormat:
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal flakes report: %w", err)
+		}
+		_, err = w.Write(append(out, '\n'))
+		return err
+	case TextFormat:
+		_, err := io.WriteString(w, r.String())
+		return err
+	default:
+		return fmt.Errorf("unknown format %q, supported formats: %s, %s", format, TextFormat, JSONFormat)
+	}
+}
+
+// String returns the report in a human-readable form.
+func (r *FlakesReport) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Analyzed %d runs.\n", r.Runs)
+	for _, t := range r.Tests {
+		status := "failing"
+		if t.Flaky {
+			status = "flaky"
+		} else if t.Failed == 0 {
+			status = "passing"
+		}
+		fmt.Fprintf(sb, "%s: suite %q, test case %q: %d runs, %d passed, %d failed, %d skipped, failure rate %.0f%%, average duration %.3fs\n",
+			status, t.Suite, t.Test, t.Runs, t.Passed, t.Failed, t.Skipped, t.FailureRate*100, t.AverageDuration)
+	}
+	return sb.String()
+}
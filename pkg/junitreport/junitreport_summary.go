@@ -0,0 +1,114 @@
+package junitreport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openshift/release/tools/junitreport/pkg/api"
+)
+
+// Summary summarizes merged JUnit results.
+type Summary struct {
+	Tests    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Duration float64
+	// Steps summarizes the results of each step.
+	Steps []StepSummary
+	// Failures lists the failed tests.
+	Failures []Failure
+}
+
+// StepSummary summarizes the results of a step.
+type StepSummary struct {
+	Step    string
+	Tests   int
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Failure describes a failed test.
+type Failure struct {
+	Step    string
+	Suite   string
+	Test    string
+	Message string
+	Output  string
+}
+
+// Summarize summarizes the merged JUnit results, bringing attention to the failed tests.
+func Summarize(suites *api.TestSuites) *Summary {
+	s := &Summary{}
+	steps := make(map[string]*StepSummary)
+	for _, suite := range suites.Suites {
+		step := Step(suite)
+		ss, ok := steps[step]
+		if !ok {
+			ss = &StepSummary{Step: step}
+			steps[step] = ss
+		}
+		s.Duration += suite.Duration
+		walkTestCases(suite, func(suiteName string, tc *api.TestCase) {
+			s.Tests++
+			ss.Tests++
+			switch {
+			case tc.SkipMessage != nil:
+				s.Skipped++
+				ss.Skipped++
+			case tc.FailureOutput != nil:
+				s.Failed++
+				ss.Failed++
+				s.Failures = append(s.Failures, Failure{
+					Step:    step,
+					Suite:   suiteName,
+					Test:    tc.Name,
+					Message: tc.FailureOutput.Message,
+					Output:  tc.FailureOutput.Output,
+				})
+			default:
+				s.Passed++
+				ss.Passed++
+			}
+		})
+	}
+	for _, ss := range steps {
+		s.Steps = append(s.Steps, *ss)
+	}
+	sort.Slice(s.Steps, func(i, j int) bool {
+		return s.Steps[i].Step < s.Steps[j].Step
+	})
+	return s
+}
+
+// String returns the summary in a human-readable form.
+func (s *Summary) String() string {
+	sb := &strings.Builder{}
+	verb := "were"
+	if s.Skipped == 1 {
+		verb = "was"
+	}
+	fmt.Fprintf(sb, "Of %d tests executed in %.3fs, %d succeeded, %d failed, and %d %s skipped.\n", s.Tests, s.Duration, s.Passed, s.Failed, s.Skipped, verb)
+	if len(s.Steps) > 1 || (len(s.Steps) == 1 && s.Steps[0].Step != "") {
+		sb.WriteString("\n")
+		for _, ss := range s.Steps {
+			step := ss.Step
+			if step == "" {
+				step = "(none)"
+			}
+			fmt.Fprintf(sb, "Step %q: %d tests, %d succeeded, %d failed, %d skipped\n", step, ss.Tests, ss.Passed, ss.Failed, ss.Skipped)
+		}
+	}
+	for _, f := range s.Failures {
+		fmt.Fprintf(sb, "\nIn suite %q, test case %q failed:\n", f.Suite, f.Test)
+		if f.Message != "" {
+			fmt.Fprintf(sb, "%s\n", f.Message)
+		}
+		if output := strings.TrimSpace(f.Output); output != "" {
+			fmt.Fprintf(sb, "%s\n", output)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package junitreport
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeAndSummarize(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"test/artifacts/junit_e2e.xml": `<testsuites>
+  <testsuite name="knative.dev/eventing/test/e2e" tests="3" failures="1" skipped="1" time="12.5">
+    <testcase name="TestBroker" time="10"></testcase>
+    <testcase name="TestTrigger" time="2.5"><failure message="timed out">trigger not ready</failure></testcase>
+    <testcase name="TestSkipped" time="0"><skipped message="not supported"></skipped></testcase>
+  </testsuite>
+</testsuites>`,
+		"knative-must-gather/artifacts/gather-knative/junit_gather.xml": `<testsuite name="must-gather" tests="1" time="1">
+  <testcase name="gather" time="1"></testcase>
+</testsuite>`,
+		"knative-must-gather/artifacts/gather-knative/namespaces/pod.xml": `not JUnit`,
+		"build-log.txt": "",
+	})
+
+	suites, err := Merge(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	for _, s := range suites.Suites {
+		steps = append(steps, Step(s)+" "+s.Name)
+	}
+	if diff := cmp.Diff([]string{"knative-must-gather must-gather", "test knative.dev/eventing/test/e2e"}, steps); diff != "" {
+		t.Errorf("Unexpected suites (-want, +got): \n%s", diff)
+	}
+
+	want := `Of 4 tests executed in 13.500s, 2 succeeded, 1 failed, and 1 was skipped.
+
+Step "knative-must-gather": 1 tests, 1 succeeded, 0 failed, 0 skipped
+Step "test": 3 tests, 1 succeeded, 1 failed, 1 skipped
+
+In suite "knative.dev/eventing/test/e2e", test case "TestTrigger" failed:
+timed out
+trigger not ready
+`
+	if diff := cmp.Diff(want, Summarize(suites).String()); diff != "" {
+		t.Errorf("Unexpected summary (-want, +got): \n%s", diff)
+	}
+
+	out := filepath.Join(dir, "junit_merged.xml")
+	if err := Write(suites, out); err != nil {
+		t.Fatal(err)
+	}
+	remerged, err := Merge(dir, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remerged.Suites) != 2 {
+		t.Errorf("Expected the merged results to be excluded, got %d suites", len(remerged.Suites))
+	}
+}
+
+func TestFlakes(t *testing.T) {
+	run := func(broker, trigger string) string {
+		return `<testsuites><testsuite name="e2e">` + broker + trigger + `</testsuite></testsuites>`
+	}
+	pass := func(name string) string {
+		return `<testcase name="` + name + `" time="2"></testcase>`
+	}
+	fail := func(name string) string {
+		return `<testcase name="` + name + `" time="4"><failure message="failed"></failure></testcase>`
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"1/test/artifacts/junit.xml": run(pass("TestBroker"), fail("TestTrigger")),
+		"2/test/artifacts/junit.xml": run(pass("TestBroker"), fail("TestTrigger")),
+		// The duration of a retried test is the duration of its final attempt.
+		"3/test/artifacts/junit.xml": run(fail("TestBroker")+pass("TestBroker"), fail("TestTrigger")),
+		"4.xml":                      run(pass("TestBroker"), ""),
+		"notes.txt":                  "",
+	})
+
+	report, err := Flakes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.Filter(1, false)
+
+	want := &FlakesReport{
+		Runs: 4,
+		Tests: []TestStatistics{
+			{Suite: "e2e", Test: "TestBroker", Runs: 4, Passed: 3, Failed: 1, FailureRate: 0.25, Flaky: true, AverageDuration: 2},
+			{Suite: "e2e", Test: "TestTrigger", Runs: 3, Failed: 3, FailureRate: 1, AverageDuration: 4},
+		},
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("Unexpected report (-want, +got): \n%s", diff)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := report.Write(buf, TextFormat); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `Analyzed 4 runs.
+flaky: suite "e2e", test case "TestBroker": 4 runs, 3 passed, 1 failed, 0 skipped, failure rate 25%, average duration 2.000s
+failing: suite "e2e", test case "TestTrigger": 3 runs, 0 passed, 3 failed, 0 skipped, failure rate 100%, average duration 4.000s
+`
+	if diff := cmp.Diff(wantText, buf.String()); diff != "" {
+		t.Errorf("Unexpected text report (-want, +got): \n%s", diff)
+	}
+}
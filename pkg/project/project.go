@@ -3,15 +3,27 @@ package project
 import (
 	"fmt"
 	"os"
+	"regexp"
 
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
+const (
+	DefaultRuntimeBaseImage      = "registry.access.redhat.com/ubi8/ubi-minimal"
+	DefaultRuntimeUser           = "65532"
+	DefaultRuntimePackageManager = "microdnf"
+)
+
+// DefaultRuntimeEntrypoint is the entrypoint of the images when none is configured.
+var DefaultRuntimeEntrypoint = []string{"/usr/bin/main"}
+
 // Metadata is the structure for project metadata
 // Every project should have a file, usually called
 // project.yaml that contains such metadata.
 type Metadata struct {
 	Project Project `json:"project" yaml:"project"`
+	// Dockerfiles configures the Dockerfiles generated for main packages.
+	Dockerfiles Dockerfiles `json:"dockerfiles" yaml:"dockerfiles"`
 }
 
 type Project struct {
@@ -19,11 +31,215 @@ type Project struct {
 	ImagePrefix string `json:"imagePrefix" yaml:"imagePrefix"`
 }
 
+// Dockerfiles configures the generated Dockerfiles, for example:
+//
+//	dockerfiles:
+//	  runtime:
+//	    baseImage: registry.access.redhat.com/ubi9/ubi-minimal
+//	    labels:
+//	      summary: Knative Eventing
+//	  overrides:
+//	    - match: cmd/webhook
+//	      runtime:
+//	        user: "1001"
+//	        packages: [ bash ]
+//	        labels:
+//	          name: eventing-webhook
+//	      build:
+//	        fips: true
+//	        ldflags: -X knative.dev/pkg/changeset.rev={{.Commit}}
+type Dockerfiles struct {
+	// Runtime configures the runtime stage of every image.
+	Runtime Runtime `json:"runtime" yaml:"runtime"`
+	// Build configures how every main package is built.
+	Build Build `json:"build" yaml:"build"`
+	// BuildImage configures the build image.
+	BuildImage BuildImage `json:"buildImage" yaml:"buildImage"`
+	// ImageNaming is how image names are derived from main package paths: base, the default, names
+	// images after the last path element and path after the path relative to the closest cmd or
+	// test_images directory, such as kafka-controller for cmd/kafka/controller.
+	ImageNaming string `json:"imageNaming" yaml:"imageNaming"`
+	// Template is the name of the project template, such as Dockerfile-webhook.tmpl, used for every
+	// main package instead of the default template.
+	// Project templates are the *.tmpl files in the generate --templates-dir directory, by default
+	// openshift/templates.
+	Template string `json:"template" yaml:"template"`
+	// Overrides configures the images of the main packages they match, in order.
+	Overrides []DockerfileOverride `json:"overrides" yaml:"overrides"`
+}
+
+// DockerfileOverride overrides the configuration of the images of the matching main packages.
+type DockerfileOverride struct {
+	// Match is a regular expression matching main package paths, such as cmd/webhook.
+	Match   string  `json:"match" yaml:"match"`
+	Runtime Runtime `json:"runtime" yaml:"runtime"`
+	Build   Build   `json:"build" yaml:"build"`
+	// Template is the name of the project template used for the matching main packages.
+	Template string `json:"template" yaml:"template"`
+	// ImageName is the name, without prefix and context, of the image of the matching main package,
+	// instead of the name derived from its path.
+	ImageName string `json:"imageName" yaml:"imageName"`
+
+	// match is the compiled Match.
+	match *regexp.Regexp
+}
+
+// Runtime configures the runtime stage of an image.
+//
+// Overrides replace the fields they set, except labels which are merged.
+type Runtime struct {
+	// BaseImage defaults to DefaultRuntimeBaseImage.
+	BaseImage string `json:"baseImage" yaml:"baseImage"`
+	// User defaults to DefaultRuntimeUser.
+	User string `json:"user" yaml:"user"`
+	// Entrypoint defaults to DefaultRuntimeEntrypoint.
+	Entrypoint []string `json:"entrypoint" yaml:"entrypoint"`
+	// Packages are installed in the runtime image with the package manager.
+	Packages []string `json:"packages" yaml:"packages"`
+	// PackageManager defaults to DefaultRuntimePackageManager.
+	PackageManager string `json:"packageManager" yaml:"packageManager"`
+	// Labels are added to the image, such as name, version, summary and io.openshift.tags.
+	Labels map[string]string `json:"labels" yaml:"labels"`
+}
+
 func ReadMetadataFile(path string) (*Metadata, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 	m := &Metadata{}
-	return m, yaml.Unmarshal(bytes, m)
+	if err := yaml.Unmarshal(bytes, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	if err := m.compileOverrides(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if err := m.Dockerfiles.Build.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// RuntimeFor returns the runtime configuration of the image of the given main package, m can be nil.
+func (m *Metadata) RuntimeFor(mainPackage string) (Runtime, error) {
+	r := Runtime{
+		BaseImage:      DefaultRuntimeBaseImage,
+		User:           DefaultRuntimeUser,
+		Entrypoint:     DefaultRuntimeEntrypoint,
+		PackageManager: DefaultRuntimePackageManager,
+	}
+	if m == nil {
+		return r, nil
+	}
+	overrides, err := m.overridesFor(mainPackage)
+	if err != nil {
+		return r, err
+	}
+	r = r.merge(m.Dockerfiles.Runtime)
+	for _, o := range overrides {
+		r = r.merge(o.Runtime)
+	}
+	return r, nil
+}
+
+// TemplateFor returns the name of the project template of the image of the given main package,
+// empty for the default template, m can be nil.
+func (m *Metadata) TemplateFor(mainPackage string) (string, error) {
+	if m == nil {
+		return "", nil
+	}
+	overrides, err := m.overridesFor(mainPackage)
+	if err != nil {
+		return "", err
+	}
+	name := m.Dockerfiles.Template
+	for _, o := range overrides {
+		if o.Template != "" {
+			name = o.Template
+		}
+	}
+	return name, nil
+}
+
+// ImageNameFor returns the explicit image name of the given main package, if any, and the image
+// naming scheme, m can be nil.
+func (m *Metadata) ImageNameFor(mainPackage string) (string, string, error) {
+	if m == nil {
+		return "", "", nil
+	}
+	overrides, err := m.overridesFor(mainPackage)
+	if err != nil {
+		return "", "", err
+	}
+	name := ""
+	for _, o := range overrides {
+		if o.ImageName != "" {
+			name = o.ImageName
+		}
+	}
+	return name, m.Dockerfiles.ImageNaming, nil
+}
+
+// overridesFor returns the overrides matching the given main package, in order.
+func (m *Metadata) overridesFor(mainPackage string) ([]DockerfileOverride, error) {
+	if err := m.compileOverrides(); err != nil {
+		return nil, err
+	}
+	var overrides []DockerfileOverride
+	for _, o := range m.Dockerfiles.Overrides {
+		if o.match.MatchString(mainPackage) {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides, nil
+}
+
+// compileOverrides compiles the match regular expressions of the overrides not compiled yet.
+func (m *Metadata) compileOverrides() error {
+	for i := range m.Dockerfiles.Overrides {
+		o := &m.Dockerfiles.Overrides[i]
+		if o.match != nil {
+			continue
+		}
+		re, err := regexp.Compile(o.Match)
+		if err != nil {
+			return fmt.Errorf("invalid Dockerfile override match %q: %w", o.Match, err)
+		}
+		o.match = re
+	}
+	return nil
+}
+
+func (r Runtime) merge(o Runtime) Runtime {
+	if o.BaseImage != "" {
+		r.BaseImage = o.BaseImage
+	}
+	if o.User != "" {
+		r.User = o.User
+	}
+	if len(o.Entrypoint) > 0 {
+		r.Entrypoint = o.Entrypoint
+	}
+	if len(o.Packages) > 0 {
+		r.Packages = o.Packages
+	}
+	if o.PackageManager != "" {
+		r.PackageManager = o.PackageManager
+	}
+	r.Labels = mergeMaps(r.Labels, o.Labels)
+	return r
+}
+
+func mergeMaps(m, o map[string]string) map[string]string {
+	if len(o) == 0 {
+		return m
+	}
+	merged := make(map[string]string, len(m)+len(o))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range o {
+		merged[k] = v
+	}
+	return merged
 }
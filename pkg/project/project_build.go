@@ -0,0 +1,191 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// FIPSBuildTag is the build tag and GOEXPERIMENT enabling the strict FIPS runtime.
+	FIPSBuildTag = "strictfipsruntime"
+
+	// VersionBuildArg, CommitBuildArg and DateBuildArg are the build arguments the ldflags
+	// template variables refer to.
+	VersionBuildArg = "VERSION"
+	CommitBuildArg  = "COMMIT"
+	DateBuildArg    = "DATE"
+)
+
+// Build configures how a main package is built.
+//
+// Overrides replace the fields they set, except env which is merged.
+type Build struct {
+	// Tags are the build tags.
+	Tags []string `json:"tags" yaml:"tags"`
+	// LDFlags are the -ldflags, a Go template with the {{.Version}}, {{.Commit}} and {{.Date}}
+	// variables, for example, -X main.version={{.Version}}.
+	LDFlags string `json:"ldflags" yaml:"ldflags"`
+	// CGOEnabled sets CGO_ENABLED, it is left unset by default.
+	CGOEnabled *bool `json:"cgoEnabled" yaml:"cgoEnabled"`
+	// TrimPath builds with -trimpath.
+	TrimPath *bool `json:"trimpath" yaml:"trimpath"`
+	// Mod sets -mod, for example, vendor.
+	Mod string `json:"mod" yaml:"mod"`
+	// FIPS builds a FIPS compliant binary with CGO_ENABLED=1, GOEXPERIMENT=strictfipsruntime
+	// and the strictfipsruntime build tag, it can't be combined with cgoEnabled: false.
+	FIPS *bool `json:"fips" yaml:"fips"`
+	// Env sets additional environment variables, such as GOEXPERIMENT.
+	Env map[string]string `json:"env" yaml:"env"`
+}
+
+// LDFlagsData is the data of the ldflags template.
+type LDFlagsData struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// BuildFor returns the build configuration of the given main package, m can be nil.
+func (m *Metadata) BuildFor(mainPackage string) (Build, error) {
+	b := Build{}
+	if m == nil {
+		return b, nil
+	}
+	overrides, err := m.overridesFor(mainPackage)
+	if err != nil {
+		return b, err
+	}
+	b = b.merge(m.Dockerfiles.Build)
+	for _, o := range overrides {
+		b = b.merge(o.Build)
+	}
+	if err := b.validate(); err != nil {
+		return b, fmt.Errorf("invalid build configuration for %s: %w", mainPackage, err)
+	}
+	return b, nil
+}
+
+// validate returns an error when FIPS is enabled and CGO is explicitly disabled, since FIPS
+// builds require CGO.
+func (b Build) validate() error {
+	if b.FIPS == nil || !*b.FIPS {
+		return nil
+	}
+	if b.CGOEnabled != nil && !*b.CGOEnabled {
+		return fmt.Errorf("fips requires cgo, it can't be combined with cgoEnabled: false")
+	}
+	if v, ok := b.Env["CGO_ENABLED"]; ok && v != "1" {
+		return fmt.Errorf("fips requires cgo, it can't be combined with env CGO_ENABLED=%s", v)
+	}
+	return nil
+}
+
+func (b Build) merge(o Build) Build {
+	if len(o.Tags) > 0 {
+		b.Tags = o.Tags
+	}
+	if o.LDFlags != "" {
+		b.LDFlags = o.LDFlags
+	}
+	if o.CGOEnabled != nil {
+		b.CGOEnabled = o.CGOEnabled
+	}
+	if o.TrimPath != nil {
+		b.TrimPath = o.TrimPath
+	}
+	if o.Mod != "" {
+		b.Mod = o.Mod
+	}
+	if o.FIPS != nil {
+		b.FIPS = o.FIPS
+	}
+	b.Env = mergeMaps(b.Env, o.Env)
+	return b
+}
+
+// Stamped returns true when the ldflags need the version build arguments.
+func (b Build) Stamped() bool {
+	return b.LDFlags != ""
+}
+
+// GoBuild returns the command building the main package to the given output.
+//
+// The ldflags template variables are rendered as references to the VersionBuildArg,
+// CommitBuildArg and DateBuildArg shell variables.
+func (b Build) GoBuild(mainPackage, output string) (string, error) {
+	if err := b.validate(); err != nil {
+		return "", err
+	}
+
+	env := mergeMaps(nil, b.Env)
+	tags := sets.NewString(b.Tags...)
+	if b.CGOEnabled != nil {
+		env = mergeMaps(env, map[string]string{"CGO_ENABLED": boolToEnv(*b.CGOEnabled)})
+	}
+	if b.FIPS != nil && *b.FIPS {
+		env = mergeMaps(env, map[string]string{"CGO_ENABLED": "1", "GOEXPERIMENT": FIPSBuildTag})
+		tags.Insert(FIPSBuildTag)
+	}
+
+	var cmd []string
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd = append(cmd, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+
+	cmd = append(cmd, "go", "build")
+	if b.Mod != "" {
+		cmd = append(cmd, "-mod="+b.Mod)
+	}
+	if b.TrimPath != nil && *b.TrimPath {
+		cmd = append(cmd, "-trimpath")
+	}
+	if tags.Len() > 0 {
+		// Keep the configured order, followed by the implied tags.
+		ordered := append([]string(nil), b.Tags...)
+		ordered = append(ordered, tags.Difference(sets.NewString(b.Tags...)).List()...)
+		cmd = append(cmd, fmt.Sprintf("-tags %q", strings.Join(ordered, ",")))
+	}
+	if b.LDFlags != "" {
+		ldflags, err := b.renderLDFlags()
+		if err != nil {
+			return "", err
+		}
+		cmd = append(cmd, fmt.Sprintf(`-ldflags "%s"`, ldflags))
+	}
+	cmd = append(cmd, "-o", output, "./"+mainPackage)
+	return strings.Join(cmd, " "), nil
+}
+
+func (b Build) renderLDFlags() (string, error) {
+	t, err := template.New("ldflags").Option("missingkey=error").Parse(b.LDFlags)
+	if err != nil {
+		return "", fmt.Errorf("invalid ldflags template %q: %w", b.LDFlags, err)
+	}
+	out := &bytes.Buffer{}
+	err = t.Execute(out, LDFlagsData{
+		Version: "${" + VersionBuildArg + "}",
+		Commit:  "${" + CommitBuildArg + "}",
+		Date:    "${" + DateBuildArg + "}",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render ldflags template %q: %w", b.LDFlags, err)
+	}
+	return out.String(), nil
+}
+
+func boolToEnv(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,180 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// ToolMethodRPM installs a tool with the package manager.
+	ToolMethodRPM = "rpm"
+	// ToolMethodGoInstall installs a tool with go install.
+	ToolMethodGoInstall = "go"
+	// ToolMethodBinary downloads a tool binary.
+	ToolMethodBinary = "binary"
+
+	DefaultBuildImagePackageManager = "yum"
+)
+
+// DefaultBuildImageTools are the tools installed in the build image when none are configured.
+var DefaultBuildImageTools = []Tool{
+	{
+		Name:    "kubectl",
+		Version: "v1.27.4",
+		Method:  ToolMethodBinary,
+		URL:     "https://dl.k8s.io/release/{{.Version}}/bin/linux/amd64/kubectl",
+	},
+	{
+		Name:   "httpd-tools",
+		Method: ToolMethodRPM,
+	},
+	{
+		Name:    "yq",
+		Version: "v3.4.1",
+		Method:  ToolMethodGoInstall,
+		Package: "github.com/mikefarah/yq/v3",
+	},
+}
+
+// BuildImage configures the image used to build and test the project in CI, for example:
+//
+//	dockerfiles:
+//	  buildImage:
+//	    tools:
+//	      - name: kubectl
+//	        version: v1.27.4
+//	        method: binary
+//	        url: https://dl.k8s.io/release/{{.Version}}/bin/linux/amd64/kubectl
+//	      - name: httpd-tools
+//	        method: rpm
+//	      - name: yq
+//	        version: v3.4.1
+//	        method: go
+//	        package: github.com/mikefarah/yq/v3
+type BuildImage struct {
+	// PackageManager installs the rpm tools, it defaults to DefaultBuildImagePackageManager.
+	PackageManager string `json:"packageManager" yaml:"packageManager"`
+	// Tools are installed in order, grouping rpm tools, DefaultBuildImageTools when unset,
+	// an empty list installs no tools.
+	Tools []Tool `json:"tools" yaml:"tools"`
+}
+
+// Tool is a tool installed in the build image.
+type Tool struct {
+	Name string `json:"name" yaml:"name"`
+	// Version is required for go and binary tools, so that builds are reproducible.
+	// For rpm tools, it is appended to the package name, such as httpd-tools-2.4.37.
+	Version string `json:"version" yaml:"version"`
+	// Method is one of rpm, go or binary.
+	Method string `json:"method" yaml:"method"`
+	// Package is the Go package of go tools, without version.
+	Package string `json:"package" yaml:"package"`
+	// URL is the download URL of binary tools, a Go template with the {{.Version}} variable.
+	URL string `json:"url" yaml:"url"`
+	// SHA256 optionally verifies the downloaded binary.
+	SHA256 string `json:"sha256" yaml:"sha256"`
+}
+
+// BuildImageConfig returns the build image configuration with defaults, m can be nil.
+func (m *Metadata) BuildImageConfig() (BuildImage, error) {
+	b := BuildImage{}
+	if m != nil {
+		b = m.Dockerfiles.BuildImage
+	}
+	if b.PackageManager == "" {
+		b.PackageManager = DefaultBuildImagePackageManager
+	}
+	if b.Tools == nil {
+		b.Tools = DefaultBuildImageTools
+	}
+	for _, t := range b.Tools {
+		if err := t.validate(); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// Instructions returns the Dockerfile instructions installing the tools.
+func (b BuildImage) Instructions() ([]string, error) {
+	var instructions []string
+	var rpms []string
+	flushRPMs := func() {
+		if len(rpms) == 0 {
+			return
+		}
+		instructions = append(instructions, fmt.Sprintf("RUN %s install -y %s && \\\n    %s clean all", b.PackageManager, strings.Join(rpms, " "), b.PackageManager))
+		rpms = nil
+	}
+	for _, t := range b.Tools {
+		switch t.Method {
+		case ToolMethodRPM:
+			rpm := t.Name
+			if t.Version != "" {
+				rpm += "-" + t.Version
+			}
+			rpms = append(rpms, rpm)
+		case ToolMethodGoInstall:
+			flushRPMs()
+			instructions = append(instructions, fmt.Sprintf("RUN GOFLAGS='' go install %s@%s", t.Package, t.Version))
+		case ToolMethodBinary:
+			flushRPMs()
+			url, err := t.renderURL()
+			if err != nil {
+				return nil, err
+			}
+			path := "/usr/local/bin/" + t.Name
+			instruction := fmt.Sprintf("RUN curl -fsSL -o %s %s && \\\n", path, url)
+			if t.SHA256 != "" {
+				instruction += fmt.Sprintf("    echo \"%s  %s\" | sha256sum -c - && \\\n", t.SHA256, path)
+			}
+			instruction += fmt.Sprintf("    chmod +x %s", path)
+			instructions = append(instructions, instruction)
+		default:
+			return nil, fmt.Errorf("build image tool %q: unknown method %q, supported methods: %s, %s, %s", t.Name, t.Method, ToolMethodRPM, ToolMethodGoInstall, ToolMethodBinary)
+		}
+	}
+	flushRPMs()
+	return instructions, nil
+}
+
+func (t Tool) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("build image tool name is required")
+	}
+	switch t.Method {
+	case ToolMethodRPM:
+		return nil
+	case ToolMethodGoInstall:
+		if t.Package == "" {
+			return fmt.Errorf("build image tool %q: package is required for %s tools", t.Name, t.Method)
+		}
+	case ToolMethodBinary:
+		if t.URL == "" {
+			return fmt.Errorf("build image tool %q: url is required for %s tools", t.Name, t.Method)
+		}
+		if _, err := t.renderURL(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("build image tool %q: unknown method %q, supported methods: %s, %s, %s", t.Name, t.Method, ToolMethodRPM, ToolMethodGoInstall, ToolMethodBinary)
+	}
+	if t.Version == "" || t.Version == "latest" {
+		return fmt.Errorf("build image tool %q: a pinned version is required for %s tools", t.Name, t.Method)
+	}
+	return nil
+}
+
+func (t Tool) renderURL() (string, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.URL)
+	if err != nil {
+		return "", fmt.Errorf("build image tool %q: invalid url template %q: %w", t.Name, t.URL, err)
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, t); err != nil {
+		return "", fmt.Errorf("build image tool %q: failed to render url template %q: %w", t.Name, t.URL, err)
+	}
+	return out.String(), nil
+}
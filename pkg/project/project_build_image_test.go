@@ -0,0 +1,181 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildImageConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *Metadata
+		want     BuildImage
+		wantErr  string
+	}{
+		{
+			name: "nil metadata",
+			want: BuildImage{PackageManager: DefaultBuildImagePackageManager, Tools: DefaultBuildImageTools},
+		},
+		{
+			name:     "nil tools install the default tools",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{PackageManager: "dnf"}}},
+			want:     BuildImage{PackageManager: "dnf", Tools: DefaultBuildImageTools},
+		},
+		{
+			name:     "empty tools install no tools",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{}}}},
+			want:     BuildImage{PackageManager: DefaultBuildImagePackageManager, Tools: []Tool{}},
+		},
+		{
+			name: "unpinned go tool",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "yq", Method: ToolMethodGoInstall, Package: "github.com/mikefarah/yq/v3"},
+			}}}},
+			wantErr: `build image tool "yq": a pinned version is required for go tools`,
+		},
+		{
+			name: "latest go tool",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "yq", Version: "latest", Method: ToolMethodGoInstall, Package: "github.com/mikefarah/yq/v3"},
+			}}}},
+			wantErr: `build image tool "yq": a pinned version is required for go tools`,
+		},
+		{
+			name: "unpinned binary tool",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "kubectl", Method: ToolMethodBinary, URL: "https://dl.k8s.io/release/stable/bin/linux/amd64/kubectl"},
+			}}}},
+			wantErr: `build image tool "kubectl": a pinned version is required for binary tools`,
+		},
+		{
+			name: "unpinned rpm tool",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "jq", Method: ToolMethodRPM},
+			}}}},
+			want: BuildImage{PackageManager: DefaultBuildImagePackageManager, Tools: []Tool{
+				{Name: "jq", Method: ToolMethodRPM},
+			}},
+		},
+		{
+			name: "go tool without package",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "yq", Version: "v3.4.1", Method: ToolMethodGoInstall},
+			}}}},
+			wantErr: `build image tool "yq": package is required for go tools`,
+		},
+		{
+			name: "binary tool with invalid url template",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "kubectl", Version: "v1.27.4", Method: ToolMethodBinary, URL: "https://dl.k8s.io/release/{{.Version"},
+			}}}},
+			wantErr: `build image tool "kubectl": invalid url template`,
+		},
+		{
+			name: "unknown method",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{BuildImage: BuildImage{Tools: []Tool{
+				{Name: "jq", Method: "apt"},
+			}}}},
+			wantErr: `build image tool "jq": unknown method "apt"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.metadata.BuildImageConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected build image (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildImageInstructions(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildImage BuildImage
+		want       []string
+		wantErr    string
+	}{
+		{
+			name:       "no tools",
+			buildImage: BuildImage{PackageManager: "dnf", Tools: []Tool{}},
+		},
+		{
+			name: "consecutive rpm tools are grouped",
+			buildImage: BuildImage{PackageManager: "dnf", Tools: []Tool{
+				{Name: "httpd-tools", Method: ToolMethodRPM},
+				{Name: "jq", Version: "1.6", Method: ToolMethodRPM},
+				{Name: "yq", Version: "v3.4.1", Method: ToolMethodGoInstall, Package: "github.com/mikefarah/yq/v3"},
+				{Name: "git", Method: ToolMethodRPM},
+			}},
+			want: []string{
+				"RUN dnf install -y httpd-tools jq-1.6 && \\\n    dnf clean all",
+				"RUN GOFLAGS='' go install github.com/mikefarah/yq/v3@v3.4.1",
+				"RUN dnf install -y git && \\\n    dnf clean all",
+			},
+		},
+		{
+			name: "binary tool",
+			buildImage: BuildImage{Tools: []Tool{
+				{Name: "kubectl", Version: "v1.27.4", Method: ToolMethodBinary, URL: "https://dl.k8s.io/release/{{.Version}}/bin/linux/amd64/kubectl"},
+			}},
+			want: []string{
+				"RUN curl -fsSL -o /usr/local/bin/kubectl https://dl.k8s.io/release/v1.27.4/bin/linux/amd64/kubectl && \\\n" +
+					"    chmod +x /usr/local/bin/kubectl",
+			},
+		},
+		{
+			name: "binary tool with sha256",
+			buildImage: BuildImage{Tools: []Tool{
+				{
+					Name:    "kubectl",
+					Version: "v1.27.4",
+					Method:  ToolMethodBinary,
+					URL:     "https://dl.k8s.io/release/{{.Version}}/bin/linux/amd64/kubectl",
+					SHA256:  "4685bfcf732260f72fce58379e812e091557ef1dfc1bc8084226c7891dd6028f",
+				},
+			}},
+			want: []string{
+				"RUN curl -fsSL -o /usr/local/bin/kubectl https://dl.k8s.io/release/v1.27.4/bin/linux/amd64/kubectl && \\\n" +
+					"    echo \"4685bfcf732260f72fce58379e812e091557ef1dfc1bc8084226c7891dd6028f  /usr/local/bin/kubectl\" | sha256sum -c - && \\\n" +
+					"    chmod +x /usr/local/bin/kubectl",
+			},
+		},
+		{
+			name: "unknown method",
+			buildImage: BuildImage{Tools: []Tool{
+				{Name: "jq", Method: "apt"},
+			}},
+			wantErr: `build image tool "jq": unknown method "apt"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.buildImage.Instructions()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected instructions (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/pointer"
+)
+
+func TestBuildGoBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   Build
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default",
+			want: "go build -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:  "cgo disabled",
+			build: Build{CGOEnabled: pointer.Bool(false)},
+			want:  "CGO_ENABLED=0 go build -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:  "mod and trimpath",
+			build: Build{Mod: "vendor", TrimPath: pointer.Bool(true)},
+			want:  "go build -mod=vendor -trimpath -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:  "trimpath disabled",
+			build: Build{TrimPath: pointer.Bool(false)},
+			want:  "go build -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:  "tags keep the configured order",
+			build: Build{Tags: []string{"netgo", "disable_gcp", "appengine"}},
+			want:  `go build -tags "netgo,disable_gcp,appengine" -o /usr/bin/main ./cmd/webhook`,
+		},
+		{
+			name:  "fips",
+			build: Build{FIPS: pointer.Bool(true), Tags: []string{"netgo", "disable_gcp"}},
+			want:  `CGO_ENABLED=1 GOEXPERIMENT=strictfipsruntime go build -tags "netgo,disable_gcp,strictfipsruntime" -o /usr/bin/main ./cmd/webhook`,
+		},
+		{
+			name:  "fips with explicit tag",
+			build: Build{FIPS: pointer.Bool(true), Tags: []string{FIPSBuildTag, "netgo"}},
+			want:  `CGO_ENABLED=1 GOEXPERIMENT=strictfipsruntime go build -tags "strictfipsruntime,netgo" -o /usr/bin/main ./cmd/webhook`,
+		},
+		{
+			name:  "fips with cgo enabled",
+			build: Build{FIPS: pointer.Bool(true), CGOEnabled: pointer.Bool(true)},
+			want:  `CGO_ENABLED=1 GOEXPERIMENT=strictfipsruntime go build -tags "strictfipsruntime" -o /usr/bin/main ./cmd/webhook`,
+		},
+		{
+			name:  "fips disabled",
+			build: Build{FIPS: pointer.Bool(false), CGOEnabled: pointer.Bool(false)},
+			want:  "CGO_ENABLED=0 go build -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:    "fips with cgo disabled",
+			build:   Build{FIPS: pointer.Bool(true), CGOEnabled: pointer.Bool(false)},
+			wantErr: "fips requires cgo",
+		},
+		{
+			name:    "fips with cgo disabled in env",
+			build:   Build{FIPS: pointer.Bool(true), Env: map[string]string{"CGO_ENABLED": "0"}},
+			wantErr: "fips requires cgo",
+		},
+		{
+			name:  "env",
+			build: Build{Env: map[string]string{"GOFLAGS": "-buildvcs=false", "GOAMD64": "v2"}},
+			want:  "GOAMD64=v2 GOFLAGS=-buildvcs=false go build -o /usr/bin/main ./cmd/webhook",
+		},
+		{
+			name:  "ldflags",
+			build: Build{LDFlags: "-X main.version={{.Version}} -X main.commit={{.Commit}} -X main.date={{.Date}}"},
+			want:  `go build -ldflags "-X main.version=${VERSION} -X main.commit=${COMMIT} -X main.date=${DATE}" -o /usr/bin/main ./cmd/webhook`,
+		},
+		{
+			name:    "invalid ldflags template",
+			build:   Build{LDFlags: "-X main.version={{.Version"},
+			wantErr: "invalid ldflags template",
+		},
+		{
+			name:    "unknown ldflags variable",
+			build:   Build{LDFlags: "-X main.branch={{.Branch}}"},
+			wantErr: "failed to render ldflags template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build.GoBuild("cmd/webhook", "/usr/bin/main")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected command (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildFor(t *testing.T) {
+	m := &Metadata{Dockerfiles: Dockerfiles{
+		Build: Build{
+			CGOEnabled: pointer.Bool(false),
+			Tags:       []string{"netgo"},
+			Env:        map[string]string{"GOFLAGS": "-buildvcs=false"},
+		},
+		Overrides: []DockerfileOverride{
+			{Match: "cmd/webhook", Build: Build{Mod: "vendor", Env: map[string]string{"GOAMD64": "v2"}}},
+			{Match: "cmd/controller", Build: Build{FIPS: pointer.Bool(true)}},
+		},
+	}}
+
+	got, err := m.BuildFor("cmd/webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Build{
+		CGOEnabled: pointer.Bool(false),
+		Tags:       []string{"netgo"},
+		Mod:        "vendor",
+		Env:        map[string]string{"GOFLAGS": "-buildvcs=false", "GOAMD64": "v2"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected build (-want, +got): \n%s", diff)
+	}
+
+	// FIPS enabled by an override on top of cgoEnabled: false is rejected.
+	if _, err := m.BuildFor("cmd/controller"); err == nil || !strings.Contains(err.Error(), "fips requires cgo") {
+		t.Errorf("want fips error, got %v", err)
+	}
+
+	if got, err := (*Metadata)(nil).BuildFor("cmd/webhook"); err != nil || !cmp.Equal(Build{}, got) {
+		t.Errorf("want empty build, got %+v, %v", got, err)
+	}
+}
+
+func TestRenderLDFlags(t *testing.T) {
+	got, err := Build{LDFlags: "-s -w -X knative.dev/pkg/changeset.rev={{.Commit}}"}.renderLDFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("-s -w -X knative.dev/pkg/changeset.rev=${COMMIT}", got); diff != "" {
+		t.Errorf("Unexpected ldflags (-want, +got): \n%s", diff)
+	}
+}
@@ -0,0 +1,299 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRuntimeFor(t *testing.T) {
+	defaults := Runtime{
+		BaseImage:      DefaultRuntimeBaseImage,
+		User:           DefaultRuntimeUser,
+		Entrypoint:     DefaultRuntimeEntrypoint,
+		PackageManager: DefaultRuntimePackageManager,
+	}
+
+	tests := []struct {
+		name        string
+		metadata    *Metadata
+		mainPackage string
+		want        Runtime
+		wantErr     bool
+	}{
+		{
+			name:        "nil metadata",
+			mainPackage: "cmd/webhook",
+			want:        defaults,
+		},
+		{
+			name:        "no configuration",
+			metadata:    &Metadata{},
+			mainPackage: "cmd/webhook",
+			want:        defaults,
+		},
+		{
+			name: "global runtime",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{
+				Runtime: Runtime{
+					BaseImage: "registry.access.redhat.com/ubi9/ubi-minimal",
+					Labels:    map[string]string{"summary": "Knative Eventing"},
+				},
+			}},
+			mainPackage: "cmd/webhook",
+			want: Runtime{
+				BaseImage:      "registry.access.redhat.com/ubi9/ubi-minimal",
+				User:           DefaultRuntimeUser,
+				Entrypoint:     DefaultRuntimeEntrypoint,
+				PackageManager: DefaultRuntimePackageManager,
+				Labels:         map[string]string{"summary": "Knative Eventing"},
+			},
+		},
+		{
+			name: "later overrides win and labels merge",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{
+				Runtime: Runtime{
+					Labels: map[string]string{"summary": "Knative Eventing", "vendor": "Red Hat"},
+				},
+				Overrides: []DockerfileOverride{
+					{
+						Match: "cmd/.*",
+						Runtime: Runtime{
+							User:     "1001",
+							Packages: []string{"bash"},
+							Labels:   map[string]string{"name": "eventing-cmd"},
+						},
+					},
+					{
+						Match: "cmd/webhook",
+						Runtime: Runtime{
+							Packages:   []string{"bash", "tar"},
+							Entrypoint: []string{"/ko-app/webhook"},
+							Labels:     map[string]string{"name": "eventing-webhook", "summary": "Knative Eventing Webhook"},
+						},
+					},
+				},
+			}},
+			mainPackage: "cmd/webhook",
+			want: Runtime{
+				BaseImage:      DefaultRuntimeBaseImage,
+				User:           "1001",
+				Entrypoint:     []string{"/ko-app/webhook"},
+				Packages:       []string{"bash", "tar"},
+				PackageManager: DefaultRuntimePackageManager,
+				Labels: map[string]string{
+					"name":    "eventing-webhook",
+					"summary": "Knative Eventing Webhook",
+					"vendor":  "Red Hat",
+				},
+			},
+		},
+		{
+			name: "not matching override",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{
+				Overrides: []DockerfileOverride{
+					{Match: "cmd/controller", Runtime: Runtime{User: "1001"}},
+				},
+			}},
+			mainPackage: "cmd/webhook",
+			want:        defaults,
+		},
+		{
+			name: "invalid match",
+			metadata: &Metadata{Dockerfiles: Dockerfiles{
+				Overrides: []DockerfileOverride{
+					{Match: "cmd/(webhook", Runtime: Runtime{User: "1001"}},
+				},
+			}},
+			mainPackage: "cmd/webhook",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.metadata.RuntimeFor(tt.mainPackage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected runtime (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRuntimeMerge(t *testing.T) {
+	base := Runtime{
+		BaseImage:      DefaultRuntimeBaseImage,
+		User:           DefaultRuntimeUser,
+		Entrypoint:     DefaultRuntimeEntrypoint,
+		Packages:       []string{"bash"},
+		PackageManager: DefaultRuntimePackageManager,
+		Labels:         map[string]string{"name": "eventing"},
+	}
+
+	tests := []struct {
+		name     string
+		override Runtime
+		want     Runtime
+	}{
+		{
+			name:     "empty override",
+			override: Runtime{},
+			want:     base,
+		},
+		{
+			name: "set fields replace",
+			override: Runtime{
+				BaseImage:      "registry.access.redhat.com/ubi9/ubi-minimal",
+				User:           "1001",
+				Entrypoint:     []string{"/ko-app/webhook"},
+				Packages:       []string{"tar"},
+				PackageManager: "dnf",
+			},
+			want: Runtime{
+				BaseImage:      "registry.access.redhat.com/ubi9/ubi-minimal",
+				User:           "1001",
+				Entrypoint:     []string{"/ko-app/webhook"},
+				Packages:       []string{"tar"},
+				PackageManager: "dnf",
+				Labels:         map[string]string{"name": "eventing"},
+			},
+		},
+		{
+			name:     "labels merge",
+			override: Runtime{Labels: map[string]string{"name": "eventing-webhook", "summary": "Webhook"}},
+			want: Runtime{
+				BaseImage:      DefaultRuntimeBaseImage,
+				User:           DefaultRuntimeUser,
+				Entrypoint:     DefaultRuntimeEntrypoint,
+				Packages:       []string{"bash"},
+				PackageManager: DefaultRuntimePackageManager,
+				Labels:         map[string]string{"name": "eventing-webhook", "summary": "Webhook"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, base.merge(tt.override)); diff != "" {
+				t.Errorf("Unexpected runtime (-want, +got): \n%s", diff)
+			}
+		})
+	}
+
+	// Merging must not modify the labels of the merged runtime.
+	if diff := cmp.Diff(map[string]string{"name": "eventing"}, base.Labels); diff != "" {
+		t.Errorf("Unexpected base labels (-want, +got): \n%s", diff)
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]string
+		o    map[string]string
+		want map[string]string
+	}{
+		{
+			name: "both nil",
+		},
+		{
+			name: "nil override",
+			m:    map[string]string{"a": "1"},
+			want: map[string]string{"a": "1"},
+		},
+		{
+			name: "nil base",
+			o:    map[string]string{"a": "1"},
+			want: map[string]string{"a": "1"},
+		},
+		{
+			name: "override wins",
+			m:    map[string]string{"a": "1", "b": "2"},
+			o:    map[string]string{"b": "3", "c": "4"},
+			want: map[string]string{"a": "1", "b": "3", "c": "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, mergeMaps(tt.m, tt.o)); diff != "" {
+				t.Errorf("Unexpected map (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadMetadataFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid overrides",
+			content: `dockerfiles:
+  overrides:
+    - match: cmd/webhook
+      runtime:
+        user: "1001"
+`,
+		},
+		{
+			name: "invalid override match",
+			content: `dockerfiles:
+  overrides:
+    - match: cmd/webhook
+    - match: cmd/(controller
+`,
+			wantErr: `invalid Dockerfile override match "cmd/(controller"`,
+		},
+		{
+			name: "fips with cgo disabled",
+			content: `dockerfiles:
+  build:
+    fips: true
+    cgoEnabled: false
+`,
+			wantErr: "fips requires cgo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "project.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			m, err := ReadMetadataFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := m.RuntimeFor("cmd/webhook")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.User != "1001" {
+				t.Errorf("want user 1001, got %q", r.User)
+			}
+		})
+	}
+
+	if _, err := ReadMetadataFile(filepath.Join("testdata", "project.yaml")); err != nil {
+		t.Error(err)
+	}
+}
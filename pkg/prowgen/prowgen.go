@@ -13,6 +13,7 @@ package prowgen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -22,8 +23,10 @@ import (
 
 	"github.com/coreos/go-semver/semver"
 	gyaml "github.com/ghodss/yaml"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowconfig "k8s.io/test-infra/prow/config"
 )
@@ -46,9 +49,24 @@ func Main() {
 	inputConfig := flag.String("config", filepath.Join("config", "repositories.yaml"), "Specify repositories config")
 	outConfig := flag.String("output", filepath.Join(openShiftRelease.Org, openShiftRelease.Repo, "ci-operator", "config"), "Specify repositories config")
 	remote := flag.String("remote", "", "openshift/release remote fork (example: git@github.com:pierDipi/release.git)")
+	reportPath := flag.String("report", "", "Write a JSON generation report to the given file, - writes it to stdout")
+	manifestPath := flag.String("manifest", "", "Manifest of the generated files (default config/manifests/<config file name>)")
+	flag.IntVar(&GenerateConcurrency, "concurrency", GenerateConcurrency, "Maximum number of configurations generated concurrently for each repository")
+	flag.StringVar(&CloneCacheDir, "cache-dir", DefaultCloneCacheDir(), "Directory holding mirror clones shared across runs, empty disables the cache")
+	gitBackend := flag.String("git-backend", ExecGitBackend, fmt.Sprintf("Backend discovery resolves branches and reads their files with, one of [%s, %s]", ExecGitBackend, GoGitBackend))
 	flag.Parse()
 
-	log.Println(*inputConfig, *outConfig)
+	reader, err := NewGitReader(*gitBackend)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	DiscoveryGitReader = reader
+
+	if *manifestPath == "" {
+		*manifestPath = DefaultManifestPath(*inputConfig)
+	}
+
+	log.Println(*inputConfig, *outConfig, *manifestPath)
 
 	in, err := os.ReadFile(*inputConfig)
 	if err != nil {
@@ -66,16 +84,27 @@ func Main() {
 		})
 	}
 
-	// Clone openshift/release and clean up existing jobs for the configured branches
+	manifest, err := ReadManifest(*manifestPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Clone openshift/release and clean up previously generated files
+	var pruned []string
 	openshiftReleaseInitialization, openshiftReleaseInitCtx := errgroup.WithContext(ctx)
 	openshiftReleaseInitialization.Go(func() error {
-		return initializeOpenShiftReleaseRepository(openshiftReleaseInitCtx, openShiftRelease, inConfig, outConfig)
+		var err error
+		pruned, err = initializeOpenShiftReleaseRepository(openshiftReleaseInitCtx, openShiftRelease, inConfig, outConfig, manifest)
+		return err
 	})
 
 	// For each repository and branch generate openshift/release configuration, and write it to the output file.
 	repositoriesGenerateConfigs, generatorsCtx := errgroup.WithContext(ctx)
-	for _, repository := range inConfig.Repositories {
-		repository := repository
+	sources := make([][]string, len(inConfig.Repositories))
+	prunedImageMirroring := make([][]string, len(inConfig.Repositories))
+	reportEntries := make([][]ReportEntry, len(inConfig.Repositories))
+	for i, repository := range inConfig.Repositories {
+		i, repository := i, repository
 
 		repositoriesGenerateConfigs.Go(func() error {
 
@@ -83,32 +112,32 @@ func Main() {
 			if err != nil {
 				return err
 			}
+			sources[i] = sourceSHAs(cfgs)
 
 			// Wait for the openshift/release initialization goroutine.
 			if err := openshiftReleaseInitialization.Wait(); err != nil {
 				return fmt.Errorf("failed waiting for %s initialization: %w", openShiftRelease.RepositoryDirectory(), err)
 			}
 
-			// Delete existing configuration for each configured branch.
-			for branch := range inConfig.Config.Branches {
-				if err := deleteExistingReleaseBuildConfigurationForBranch(outConfig, repository, branch); err != nil {
-					return err
-				}
-			}
-
 			// Write generated configurations.
 			for _, cfg := range cfgs {
 				if err := saveReleaseBuildConfiguration(outConfig, cfg); err != nil {
 					return err
 				}
+				manifest.Add(filepath.Join(*outConfig, cfg.Path))
 			}
 
 			// Generate and write image mirroring configurations.
-			for _, imageMirroring := range GenerateImageMirroringConfigs(openShiftRelease, cfgs) {
-				if err := ReconcileImageMirroringConfig(imageMirroring); err != nil {
+			imageMirroringConfigs := GenerateImageMirroringConfigs(openShiftRelease, cfgs)
+			for _, imageMirroring := range imageMirroringConfigs {
+				deleted, err := ReconcileImageMirroringConfig(imageMirroring, manifest)
+				if err != nil {
 					return err
 				}
+				prunedImageMirroring[i] = append(prunedImageMirroring[i], deleted...)
 			}
+
+			reportEntries[i] = NewReportEntries(*outConfig, cfgs, imageMirroringConfigs)
 			return nil
 		})
 	}
@@ -120,6 +149,26 @@ func Main() {
 	if err := repositoriesGenerateConfigs.Wait(); err != nil {
 		log.Fatalln("Failed waiting for repositories generator", err)
 	}
+	for _, deleted := range prunedImageMirroring {
+		pruned = append(pruned, deleted...)
+	}
+
+	if err := manifest.Write(*manifestPath); err != nil {
+		log.Fatalln("Failed to write manifest", err)
+	}
+
+	if *reportPath != "" {
+		report := &Report{
+			Config:       *inputConfig,
+			DeletedFiles: sets.NewString(pruned...).Difference(sets.NewString(manifest.Files...)).List(),
+		}
+		for _, entries := range reportEntries {
+			report.Entries = append(report.Entries, entries...)
+		}
+		if err := report.Write(*reportPath); err != nil {
+			log.Fatalln("Failed to write report", err)
+		}
+	}
 
 	if err := runOpenShiftReleaseGenerator(ctx, openShiftRelease); err != nil {
 		log.Fatalln("Failed to run openshift/release generator:", err)
@@ -130,12 +179,22 @@ func Main() {
 	if err := runOpenShiftReleaseGenerator(ctx, openShiftRelease); err != nil {
 		log.Fatalln("Failed to run openshift/release generator after injecting Slack reporter", err)
 	}
-	if err := pushBranch(ctx, openShiftRelease, remote, "sync-serverless-ci", *inputConfig); err != nil {
+	if err := pushBranch(ctx, openShiftRelease, remote, "sync-serverless-ci", *inputConfig, sources); err != nil {
 		log.Fatalln("Failed to push branch to openshift/release fork", *remote, err)
 	}
 }
 
-func pushBranch(ctx context.Context, release Repository, remote *string, branch string, config string) error {
+// sourceSHAs returns the sorted list of "<org>/<repo> <branch> <sha>" the given configurations have been
+// generated from.
+func sourceSHAs(cfgs []ReleaseBuildConfiguration) []string {
+	shas := sets.NewString()
+	for _, cfg := range cfgs {
+		shas.Insert(fmt.Sprintf("%s/%s %s %s", cfg.Metadata.Org, cfg.Metadata.Repo, cfg.Branch, cfg.SourceSHA))
+	}
+	return shas.List()
+}
+
+func pushBranch(ctx context.Context, release Repository, remote *string, branch string, config string, sources [][]string) error {
 	if remote == nil || *remote == "" {
 		return nil
 	}
@@ -150,34 +209,23 @@ func pushBranch(ctx context.Context, release Repository, remote *string, branch
 	if _, err := run(ctx, release, "git", "add", "."); err != nil {
 		return err
 	}
-	if _, err := run(ctx, release, "git", "commit", "-s", "-S", "-m", "Sync Serverless CI "+config); err != nil {
+	message := "Sync Serverless CI " + config + "\n\nGenerated from:\n"
+	for _, s := range sources {
+		for _, source := range s {
+			message += "- " + source + "\n"
+		}
+	}
+	if _, err := run(ctx, release, "git", "commit", "-s", "-S", "-m", message); err != nil {
 		// Ignore error since we could have nothing to commit
 		log.Println("Ignored error", err)
 	}
-	if _, err := run(ctx, release, "git", "push", "fork", branch, "-f"); err != nil {
+	if _, err := runGitNetwork(ctx, release, release.RepositoryDirectory(), nil, "push", "fork", branch, "-f"); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func deleteExistingReleaseBuildConfigurationForBranch(outConfig *string, r Repository, branch string) error {
-	dir := filepath.Join(*outConfig, r.RepositoryDirectory())
-	matches, err := filepath.Glob(filepath.Join(dir, "*"+branch+"*"))
-	if err != nil {
-		return err
-	}
-
-	for _, match := range matches {
-		log.Println("Detected a new config for branch", branch, "removing file", match)
-		if err := os.Remove(match); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func saveReleaseBuildConfiguration(outConfig *string, cfg ReleaseBuildConfiguration) error {
 	dir := filepath.Join(*outConfig, filepath.Dir(cfg.Path))
 
@@ -215,12 +263,12 @@ func injectSlackReporterConfig(inConfig *Config, openShiftRelease Repository) er
 	for branch := range inConfig.Config.Branches {
 		for _, r := range inConfig.Repositories {
 			generatedOutputDir := "ci-operator/jobs"
-			glob := filepath.Join(openShiftRelease.RepositoryDirectory(), generatedOutputDir, r.RepositoryDirectory(), "*"+branch+"*periodics*")
-			matches, err := filepath.Glob(glob)
-			if err != nil {
-				return err
+			metadata := cioperatorapi.Metadata{Org: r.Org, Repo: r.Repo, Branch: branch}
+			periodics := filepath.Join(openShiftRelease.RepositoryDirectory(), generatedOutputDir, metadata.JobFilePath("periodics"))
+			if _, err := os.Stat(periodics); errors.Is(err, os.ErrNotExist) {
+				continue
 			}
-			for _, match := range matches {
+			for _, match := range []string{periodics} {
 				// Going directly from YAML raw input produces unexpected configs (due to missing YAML tags),
 				// so we convert YAML to JSON and unmarshal the struct from the JSON object.
 				y, err := os.ReadFile(match)
@@ -271,27 +319,28 @@ func injectSlackReporterConfig(inConfig *Config, openShiftRelease Repository) er
 	return nil
 }
 
-// initializeOpenShiftReleaseRepository clones openshift/release and clean up existing jobs
-// for the configured branches
-func initializeOpenShiftReleaseRepository(ctx context.Context, openShiftRelease Repository, inConfig *Config, outputConfig *string) error {
+// initializeOpenShiftReleaseRepository clones openshift/release and clean up files previously
+// generated by prowgen, which are files listed in the manifest and configurations for the
+// configured branches, it returns the deleted files.
+func initializeOpenShiftReleaseRepository(ctx context.Context, openShiftRelease Repository, inConfig *Config, outputConfig *string, manifest *Manifest) ([]string, error) {
 	if err := GitClone(ctx, openShiftRelease); err != nil {
-		return err
+		return nil, err
 	}
 	if err := GitCheckout(ctx, openShiftRelease, "master"); err != nil {
-		return err
+		return nil, err
+	}
+	deleted, err := manifest.Prune()
+	if err != nil {
+		return nil, err
 	}
 	for branch := range inConfig.Config.Branches {
 		for _, r := range inConfig.Repositories {
-			matches, err := filepath.Glob(filepath.Join(*outputConfig, r.RepositoryDirectory(), "*"+branch+"*"))
+			files, err := pruneGeneratedReleaseBuildConfigurations(*outputConfig, r, branch)
 			if err != nil {
-				return err
-			}
-			for _, match := range matches {
-				if err := os.Remove(match); err != nil {
-					return err
-				}
+				return nil, err
 			}
+			deleted = append(deleted, files...)
 		}
 	}
-	return nil
+	return deleted, nil
 }
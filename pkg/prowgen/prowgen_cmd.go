@@ -3,50 +3,230 @@ package prowgen
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
 )
 
-func runNoRepo(ctx context.Context, name string, args ...string) ([]byte, error) {
-	var buf bytes.Buffer
+const (
+	// gitNetworkTimeout is the timeout of a single attempt of git operations going through the network.
+	gitNetworkTimeout = 10 * time.Minute
+	// gitNetworkRetries is the number of retries of git operations going through the network.
+	gitNetworkRetries = 3
+)
+
+// Command is a command to run.
+type Command struct {
+	Name string
+	Args []string
+	// Dir is the working directory of the command, empty means the current working directory.
+	Dir string
+	// Prefix is prepended to every line of stdout and stderr of the command, for example, [org/repo].
+	Prefix string
+	// Timeout is the timeout of a single attempt, 0 means no timeout.
+	Timeout time.Duration
+	// Retries is the number of retries when the command fails.
+	Retries int
+	// BeforeRetry is called before every retry, for example, to clean up a partial clone.
+	BeforeRetry func() error
+	// Quiet disables streaming stdout, for example, when reading files.
+	Quiet bool
+}
+
+func (c Command) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// Runner runs commands.
+type Runner interface {
+	// Run runs the given command and returns its stdout.
+	Run(ctx context.Context, cmd Command) ([]byte, error)
+}
+
+// ExecRunner is a Runner running commands using os/exec.
+//
+// Commands are killed when the context is done.
+type ExecRunner struct {
+	// Stdout receives the stdout of commands prefixed with Command.Prefix.
+	Stdout io.Writer
+	// Stderr receives the stderr of commands prefixed with Command.Prefix.
+	Stderr io.Writer
+	// Backoff is the time to wait before retrying a failed command.
+	Backoff time.Duration
+}
+
+// DefaultRunner is the Runner used for every command, it can be swapped for a fake in tests.
+var DefaultRunner Runner = &ExecRunner{
+	Stdout:  os.Stdout,
+	Stderr:  os.Stderr,
+	Backoff: 5 * time.Second,
+}
+
+func (e *ExecRunner) Run(ctx context.Context, cmd Command) ([]byte, error) {
+	var err error
+	for attempt := 0; attempt <= cmd.Retries; attempt++ {
+		if attempt > 0 {
+			log.Println(cmd.Prefix, "Retrying", cmd.String(), "attempt", attempt, "error", err)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(e.Backoff):
+			}
+			if cmd.BeforeRetry != nil {
+				if err := cmd.BeforeRetry(); err != nil {
+					return nil, fmt.Errorf("failed to prepare retry of %s: %w", cmd.String(), err)
+				}
+			}
+		}
+
+		var out []byte
+		out, err = e.run(ctx, cmd)
+		if err == nil {
+			return out, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (e *ExecRunner) run(ctx context.Context, cmd Command) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
 
-	select {
-	case <-ctx.Done():
-		return buf.Bytes(), ctx.Err()
-	default:
+	var stdout, stderr bytes.Buffer
+	stdoutWriter := newPrefixWriter(cmd.Prefix, e.Stdout)
+	if cmd.Quiet {
+		stdoutWriter = newPrefixWriter(cmd.Prefix, io.Discard)
 	}
+	stderrWriter := newPrefixWriter(cmd.Prefix, e.Stderr)
 
-	cmd := exec.Command(name, args...)
+	c := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	c.Dir = cmd.Dir
+	c.Stdout = io.MultiWriter(stdoutWriter, &stdout)
+	c.Stderr = io.MultiWriter(stderrWriter, &stderr)
 
-	cmd.Stdout = io.MultiWriter(os.Stdout, &buf)
-	cmd.Stderr = os.Stderr
+	err := c.Run()
+	stdoutWriter.Flush()
+	stderrWriter.Flush()
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to run %s %v: %w", name, args, err)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%w: %v", ctxErr, err)
+		}
+		return nil, fmt.Errorf("failed to run %s %v: %w: %s", cmd.Name, cmd.Args, err, strings.TrimSpace(stderr.String()))
 	}
+	return stdout.Bytes(), nil
+}
+
+// prefixWriter writes every line prefixed with the given prefix to the underlying writer.
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
 
-	return buf.Bytes(), nil
+	mu  sync.Mutex
+	buf bytes.Buffer
 }
 
-func run(ctx context.Context, r Repository, name string, args ...string) ([]byte, error) {
-	var buf bytes.Buffer
+func newPrefixWriter(prefix string, w io.Writer) *prefixWriter {
+	if w == nil {
+		w = io.Discard
+	}
+	if prefix != "" {
+		prefix = "[" + prefix + "] "
+	}
+	return &prefixWriter{prefix: prefix, w: w}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := p.buf.Next(i + 1)
+		if _, err := p.w.Write(append([]byte(p.prefix), line...)); err != nil {
+			return len(b), err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes the remaining partial line, if any.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	select {
-	case <-ctx.Done():
-		return buf.Bytes(), ctx.Err()
-	default:
+	if p.buf.Len() == 0 {
+		return
 	}
+	_, _ = p.w.Write(append([]byte(p.prefix), append(p.buf.Bytes(), '\n')...))
+	p.buf.Reset()
+}
 
-	cmd := exec.Command(name, args...)
+func run(ctx context.Context, r Repository, name string, args ...string) ([]byte, error) {
+	out, err := DefaultRunner.Run(ctx, Command{
+		Name:   name,
+		Args:   args,
+		Dir:    r.RepositoryDirectory(),
+		Prefix: r.RepositoryDirectory(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+	}
+	return out, nil
+}
 
-	cmd.Dir = r.RepositoryDirectory()
-	cmd.Stdout = io.MultiWriter(os.Stdout, &buf)
-	cmd.Stderr = os.Stderr
+// runQuiet is like run but it doesn't stream stdout.
+func runQuiet(ctx context.Context, r Repository, name string, args ...string) ([]byte, error) {
+	out, err := DefaultRunner.Run(ctx, Command{
+		Name:   name,
+		Args:   args,
+		Dir:    r.RepositoryDirectory(),
+		Prefix: r.RepositoryDirectory(),
+		Quiet:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+	}
+	return out, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("[%s] failed to run %s %v: %w", r.RepositoryDirectory(), name, args, err)
+// runGitNetwork runs git operations going through the network, such as clone, fetch and push,
+// with a timeout and retries.
+func runGitNetwork(ctx context.Context, r Repository, dir string, beforeRetry func() error, args ...string) ([]byte, error) {
+	out, err := DefaultRunner.Run(ctx, Command{
+		Name:        "git",
+		Args:        args,
+		Dir:         dir,
+		Prefix:      r.RepositoryDirectory(),
+		Timeout:     gitNetworkTimeout,
+		Retries:     gitNetworkRetries,
+		BeforeRetry: beforeRetry,
+	})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("[%s] timed out after %s: %w", r.RepositoryDirectory(), gitNetworkTimeout, err)
+		}
+		return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
 	}
-	return buf.Bytes(), nil
+	return out, nil
 }
@@ -0,0 +1,135 @@
+package prowgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeRunner struct {
+	commands []Command
+	outputs  map[string][]byte
+	errs     map[string]error
+}
+
+func (f *fakeRunner) Run(_ context.Context, cmd Command) ([]byte, error) {
+	f.commands = append(f.commands, cmd)
+	return f.outputs[cmd.String()], f.errs[cmd.String()]
+}
+
+func withFakeRunner(t *testing.T, f *fakeRunner) {
+	previous := DefaultRunner
+	DefaultRunner = f
+	t.Cleanup(func() { DefaultRunner = previous })
+}
+
+func TestExecRunnerPrefixesOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	runner := &ExecRunner{Stdout: &stdout, Stderr: &stderr}
+
+	out, err := runner.Run(context.Background(), Command{
+		Name:   "sh",
+		Args:   []string{"-c", "echo hello; echo world; echo oops >&2; printf partial"},
+		Prefix: "org/repo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff("hello\nworld\npartial", string(out)); diff != "" {
+		t.Errorf("Unexpected output (-want, +got): \n%s", diff)
+	}
+	if diff := cmp.Diff("[org/repo] hello\n[org/repo] world\n[org/repo] partial\n", stdout.String()); diff != "" {
+		t.Errorf("Unexpected stdout (-want, +got): \n%s", diff)
+	}
+	if diff := cmp.Diff("[org/repo] oops\n", stderr.String()); diff != "" {
+		t.Errorf("Unexpected stderr (-want, +got): \n%s", diff)
+	}
+}
+
+func TestExecRunnerRetries(t *testing.T) {
+	runner := &ExecRunner{}
+
+	retries := 0
+	_, err := runner.Run(context.Background(), Command{
+		Name:    "sh",
+		Args:    []string{"-c", "echo failed >&2; exit 1"},
+		Retries: 2,
+		BeforeRetry: func() error {
+			retries++
+			return nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "failed") {
+		t.Errorf("expected error to contain stderr, got %v", err)
+	}
+	if retries != 2 {
+		t.Errorf("expected 2 retries, got %d", retries)
+	}
+}
+
+func TestExecRunnerTimeout(t *testing.T) {
+	runner := &ExecRunner{}
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), Command{
+		Name:    "sleep",
+		Args:    []string{"10"},
+		Timeout: 100 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("command wasn't killed on timeout")
+	}
+}
+
+func TestExecRunnerCancel(t *testing.T) {
+	runner := &ExecRunner{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := runner.Run(ctx, Command{
+		Name:    "sleep",
+		Args:    []string{"10"},
+		Retries: 3,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("command wasn't killed on cancel")
+	}
+}
+
+func TestGitFetchFakeRunner(t *testing.T) {
+	f := &fakeRunner{}
+	withFakeRunner(t, f)
+
+	r := Repository{Org: "openshift-knative", Repo: "eventing"}
+	if err := GitFetch(context.Background(), r, "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.commands) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(f.commands))
+	}
+	got := f.commands[0]
+	if got.String() != "git fetch https://github.com/openshift-knative/eventing.git abc" {
+		t.Errorf("unexpected command %s", got.String())
+	}
+	if got.Dir != r.RepositoryDirectory() || got.Retries != gitNetworkRetries || got.Timeout != gitNetworkTimeout {
+		t.Errorf("unexpected command configuration %+v", got)
+	}
+}
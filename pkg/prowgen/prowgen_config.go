@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"golang.org/x/sync/errgroup"
 )
 
 type Repository struct {
@@ -20,10 +24,30 @@ type Repository struct {
 	Images                []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration `json:"images" yaml:"images"`
 	Tests                 []cioperatorapi.TestStepConfiguration                       `json:"tests" yaml:"tests"`
 	Resources             cioperatorapi.ResourceConfiguration                         `json:"resources" yaml:"resources"`
+	ResourceProfiles      RepositoryResourceProfiles                                  `json:"resourceProfiles" yaml:"resourceProfiles"`
+
+	// ref is the commit discovery reads files at with DiscoveryGitReader, empty reads files from
+	// the working directory.
+	ref string
 }
 
 type E2ETests struct {
 	Matches []string `json:"matches" yaml:"matches"`
+
+	// Dependencies declares the images tests depend on, tests not matching any
+	// entry depend on every image, unless InferDependencies is enabled.
+	Dependencies []TestDependencies `json:"dependencies" yaml:"dependencies"`
+	// InferDependencies enables the inference of the images a test depends on by
+	// scanning the Makefile target recipe and the scripts it runs for image env
+	// variables (for example, KNATIVE_EVENTING_DISPATCHER).
+	InferDependencies bool `json:"inferDependencies" yaml:"inferDependencies"`
+}
+
+type TestDependencies struct {
+	// Matches are Makefile targets regular expressions.
+	Matches []string `json:"matches" yaml:"matches"`
+	// Images are image names regular expressions.
+	Images []string `json:"images" yaml:"images"`
 }
 
 func (r Repository) RepositoryDirectory() string {
@@ -36,6 +60,10 @@ type Branch struct {
 
 type CommonConfig struct {
 	Branches map[string]Branch `json:"branches" yaml:"branches"`
+
+	// ResourceProfiles overrides or adds resource profiles to the default ones
+	// (small, medium and large).
+	ResourceProfiles ResourceProfiles `json:"resourceProfiles" yaml:"resourceProfiles"`
 }
 
 type ReleaseBuildConfigurationOption func(cfg *cioperatorapi.ReleaseBuildConfiguration) error
@@ -47,8 +75,14 @@ type ReleaseBuildConfiguration struct {
 
 	Path   string
 	Branch string
+	// SourceSHA is the resolved SHA of Branch the configuration has been generated from.
+	SourceSHA string
 }
 
+// GenerateConcurrency is the maximum number of configurations generated concurrently for
+// a repository.
+var GenerateConcurrency = runtime.NumCPU()
+
 func NewGenerateConfigs(ctx context.Context, r Repository, cc CommonConfig, opts ...ReleaseBuildConfigurationOption) ([]ReleaseBuildConfiguration, error) {
 
 	cfgs := make([]ReleaseBuildConfiguration, 0, len(cc.Branches)*2)
@@ -57,100 +91,161 @@ func NewGenerateConfigs(ctx context.Context, r Repository, cc CommonConfig, opts
 		return nil, err
 	}
 
-	for branchName, branch := range cc.Branches {
+	profiles := cc.ResourceProfiles.WithDefaults()
 
-		if err := GitCheckout(ctx, r, branchName); err != nil {
-			return nil, fmt.Errorf("[%s] failed to checkout branch %s", r.RepositoryDirectory(), branchName)
+	// Each branch is read at its commit with DiscoveryGitReader, so that branches are generated
+	// concurrently without checking out branches. Every branch is resolved before starting any
+	// generator, so that a failure doesn't leave generators running.
+	type branchRef struct {
+		name      string
+		branch    Branch
+		sourceSHA string
+		r         Repository
+	}
+	branchNames := make([]string, 0, len(cc.Branches))
+	for branchName := range cc.Branches {
+		branchNames = append(branchNames, branchName)
+	}
+	sort.Strings(branchNames)
+
+	branches := make([]branchRef, 0, len(branchNames))
+	for _, branchName := range branchNames {
+		sourceSHA, err := DiscoveryGitReader.RevParse(ctx, r, branchName)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] failed to resolve branch %s: %w", r.RepositoryDirectory(), branchName, err)
 		}
 
-		isFirstVersion := true
-		for _, ov := range branch.OpenShiftVersions {
+		br := r
+		br.ref = sourceSHA
+		branches = append(branches, branchRef{name: branchName, branch: cc.Branches[branchName], sourceSHA: sourceSHA, r: br})
+	}
+
+	generators, generatorsCtx := errgroup.WithContext(ctx)
+	generators.SetLimit(GenerateConcurrency)
+	var lock sync.Mutex
 
-			log.Println(r.RepositoryDirectory(), "Generating config", branchName, "OpenShiftVersion", ov)
+	for _, b := range branches {
+		b := b
 
-			variant := strings.ReplaceAll(ov, ".", "")
+		for i, ov := range b.branch.OpenShiftVersions {
+			isFirstVersion, ov := i == 0, ov
 
-			images := make([]cioperatorapi.ProjectDirectoryImageBuildStepConfiguration, 0, len(r.Images))
-			for _, img := range r.Images {
-				images = append(images, *img.DeepCopy())
-			}
+			generators.Go(func() error {
+				if err := generatorsCtx.Err(); err != nil {
+					return err
+				}
 
-			tests := make([]cioperatorapi.TestStepConfiguration, 0, len(r.Tests))
-			for _, test := range r.Tests {
-				tests = append(tests, *test.DeepCopy())
-			}
+				cfg, err := newGenerateConfig(b.r, b.name, b.sourceSHA, ov, isFirstVersion, profiles, opts...)
+				if err != nil {
+					return err
+				}
 
-			resources := make(cioperatorapi.ResourceConfiguration, 1)
-			resources["*"] = cioperatorapi.ResourceRequirements{
-				Requests: map[string]string{
-					"cpu":    "500m",
-					"memory": "1Gi",
-				},
-			}
-			for k, v := range r.Resources {
-				resources[k] = v
-			}
-
-			cfg := cioperatorapi.ReleaseBuildConfiguration{
-				Metadata: cioperatorapi.Metadata{
-					Org:     r.Org,
-					Repo:    r.Repo,
-					Branch:  branchName,
-					Variant: variant,
-				},
-				InputConfiguration: cioperatorapi.InputConfiguration{
-					BuildRootImage: &cioperatorapi.BuildRootImageConfiguration{
-						ProjectImageBuild: &cioperatorapi.ProjectDirectoryImageBuildInputs{
-							DockerfilePath: "openshift/ci-operator/build-image/Dockerfile",
-						},
-					},
-				},
-				CanonicalGoRepository: r.CanonicalGoRepository,
-				Images:                images,
-				Tests:                 tests,
-				Resources:             resources,
-			}
-
-			options := make([]ReleaseBuildConfigurationOption, 0, len(opts))
-			copy(options, opts)
-			if isFirstVersion {
-				isFirstVersion = false
-				options = append(options, withNamePromotion(r, branchName))
-			} else {
-				options = append(options, withTagPromotion(r, branchName))
-			}
-
-			options = append(
-				options,
-				DiscoverImages(r),
-				DiscoverTests(r, ov),
-			)
-
-			log.Println(r.RepositoryDirectory(), "Apply input options", len(options))
-
-			if err := applyOptions(&cfg, options...); err != nil {
-				return nil, fmt.Errorf("[%s] failed to apply option: %w", r.RepositoryDirectory(), err)
-			}
-
-			log.Println("numTests", len(cfg.Tests), "numImages", len(cfg.Images))
-
-			// openshift-knative/eventing-kafka-broker/openshift-knative-eventing-kafka-broker-release-next__411.yaml
-			buildConfigPath := filepath.Join(
-				r.RepositoryDirectory(),
-				r.Org+"-"+r.Repo+"-"+branchName+"__"+variant+".yaml",
-			)
-
-			cfgs = append(cfgs, ReleaseBuildConfiguration{
-				ReleaseBuildConfiguration: cfg,
-				Path:                      buildConfigPath,
-				Branch:                    branchName,
+				lock.Lock()
+				defer lock.Unlock()
+				cfgs = append(cfgs, cfg)
+				return nil
 			})
 		}
 	}
 
+	if err := generators.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(cfgs, func(i, j int) bool {
+		return cfgs[i].Path < cfgs[j].Path
+	})
+
+	if err := DefaultNamingPolicy.Validate(cfgs); err != nil {
+		return nil, fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+	}
+
 	return cfgs, nil
 }
 
+func newGenerateConfig(r Repository, branchName string, sourceSHA string, ov string, isFirstVersion bool, profiles ResourceProfiles, opts ...ReleaseBuildConfigurationOption) (ReleaseBuildConfiguration, error) {
+	log.Println(r.RepositoryDirectory(), "Generating config", branchName, "SHA", sourceSHA, "OpenShiftVersion", ov)
+
+	variant := strings.ReplaceAll(ov, ".", "")
+
+	images := make([]cioperatorapi.ProjectDirectoryImageBuildStepConfiguration, 0, len(r.Images))
+	for _, img := range r.Images {
+		images = append(images, *img.DeepCopy())
+	}
+
+	tests := make([]cioperatorapi.TestStepConfiguration, 0, len(r.Tests))
+	for _, test := range r.Tests {
+		tests = append(tests, *test.DeepCopy())
+	}
+
+	resources := make(cioperatorapi.ResourceConfiguration, 1)
+	resources["*"] = cioperatorapi.ResourceRequirements{
+		Requests: map[string]string{
+			"cpu":    "500m",
+			"memory": "1Gi",
+		},
+	}
+	for k, v := range r.Resources {
+		resources[k] = v
+	}
+
+	cfg := cioperatorapi.ReleaseBuildConfiguration{
+		Metadata: cioperatorapi.Metadata{
+			Org:     r.Org,
+			Repo:    r.Repo,
+			Branch:  branchName,
+			Variant: variant,
+		},
+		InputConfiguration: cioperatorapi.InputConfiguration{
+			BuildRootImage: &cioperatorapi.BuildRootImageConfiguration{
+				ProjectImageBuild: &cioperatorapi.ProjectDirectoryImageBuildInputs{
+					DockerfilePath: "openshift/ci-operator/build-image/Dockerfile",
+				},
+			},
+		},
+		CanonicalGoRepository: r.CanonicalGoRepository,
+		Images:                images,
+		Tests:                 tests,
+		Resources:             resources,
+	}
+
+	options := make([]ReleaseBuildConfigurationOption, 0, len(opts)+4)
+	options = append(options, opts...)
+	if isFirstVersion {
+		options = append(options, withNamePromotion(r, branchName))
+	} else {
+		options = append(options, withTagPromotion(r, branchName))
+	}
+
+	options = append(
+		options,
+		DiscoverImages(r),
+		WithImagesResources(r, profiles),
+		DiscoverTests(r, ov, profiles),
+	)
+
+	log.Println(r.RepositoryDirectory(), "Apply input options", len(options))
+
+	if err := applyOptions(&cfg, options...); err != nil {
+		return ReleaseBuildConfiguration{}, fmt.Errorf("[%s] failed to apply option: %w", r.RepositoryDirectory(), err)
+	}
+
+	log.Println(r.RepositoryDirectory(), branchName, ov, "numTests", len(cfg.Tests), "numImages", len(cfg.Images))
+
+	// openshift-knative/eventing-kafka-broker/openshift-knative-eventing-kafka-broker-release-next__411.yaml
+	buildConfigPath := filepath.Join(
+		r.RepositoryDirectory(),
+		r.Org+"-"+r.Repo+"-"+branchName+"__"+variant+".yaml",
+	)
+
+	return ReleaseBuildConfiguration{
+		ReleaseBuildConfiguration: cfg,
+		Path:                      buildConfigPath,
+		Branch:                    branchName,
+		SourceSHA:                 sourceSHA,
+	}, nil
+}
+
 // TODO: In 2023 we need to move forward to use the new `eventing`, for _new_ repos,
 // The tool should only generate desired updates, always all
 func transformLegacyKnativeEventingSourceImageName(r Repository) string {
@@ -0,0 +1,201 @@
+package prowgen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestNewGenerateConfigsFromRefs(t *testing.T) {
+	ctx := context.Background()
+
+	upstream := newTestGitRepository(t)
+	mustGit(t, upstream, "checkout", "-q", "-b", "release-v1.0")
+	mustWriteFile(t, upstream, "Makefile", "test-e2e:\n\techo v1.0\n")
+	mustWriteFile(t, upstream, "openshift/ci-operator/knative-images/controller/Dockerfile", "FROM scratch\n")
+	mustGit(t, upstream, "add", ".")
+	mustGit(t, upstream, "commit", "-q", "-m", "release-v1.0")
+
+	mustGit(t, upstream, "checkout", "-q", "-b", "release-v1.1")
+	mustWriteFile(t, upstream, "Makefile", "test-e2e:\n\techo v1.1\n\ntest-conformance:\n\techo v1.1\n")
+	mustWriteFile(t, upstream, "openshift/ci-operator/knative-images/webhook/Dockerfile", "FROM scratch\n")
+	mustGit(t, upstream, "add", ".")
+	mustGit(t, upstream, "commit", "-q", "-m", "release-v1.1")
+
+	previousRemoteURL, previousCacheDir := gitRemoteURL, CloneCacheDir
+	t.Cleanup(func() { gitRemoteURL, CloneCacheDir = previousRemoteURL, previousCacheDir })
+	gitRemoteURL = func(r Repository) string { return upstream.RepositoryDirectory() }
+	CloneCacheDir = ""
+
+	previousReader := DiscoveryGitReader
+	t.Cleanup(func() { DiscoveryGitReader = previousReader })
+
+	for _, backend := range []string{ExecGitBackend, GoGitBackend} {
+		t.Run(backend, func(t *testing.T) {
+			reader, err := NewGitReader(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			DiscoveryGitReader = reader
+
+			r := Repository{
+				Org:         t.TempDir(),
+				Repo:        "serving",
+				ImagePrefix: "knative-serving",
+				E2ETests: E2ETests{
+					Matches: []string{"test-.*"},
+				},
+			}
+			cc := CommonConfig{
+				Branches: map[string]Branch{
+					"release-v1.0": {OpenShiftVersions: []string{"4.11", "4.12"}},
+					"release-v1.1": {OpenShiftVersions: []string{"4.11", "4.12"}},
+				},
+			}
+
+			cfgs, err := NewGenerateConfigs(ctx, r, cc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			type summary struct {
+				Path      string
+				SourceSHA string
+				Images    []string
+				Tests     []string
+			}
+
+			shas := map[string]string{}
+			for branch := range cc.Branches {
+				sha, err := GitRevParse(ctx, upstream, branch)
+				if err != nil {
+					t.Fatal(err)
+				}
+				shas[branch] = sha
+			}
+
+			var got []summary
+			for _, cfg := range cfgs {
+				s := summary{Path: filepath.Base(cfg.Path), SourceSHA: cfg.SourceSHA}
+				for _, img := range cfg.Images {
+					s.Images = append(s.Images, string(img.To))
+				}
+				for _, test := range cfg.Tests {
+					s.Tests = append(s.Tests, test.As)
+				}
+				got = append(got, s)
+			}
+
+			want := []summary{
+				{
+					Path:      filepath.Base(r.Org) + "-serving-release-v1.0__411.yaml",
+					SourceSHA: shas["release-v1.0"],
+					Images:    []string{"knative-serving-controller"},
+					Tests:     []string{"test-e2e-aws-ocp-411", "test-e2e-aws-ocp-411-continuous"},
+				},
+				{
+					Path:      filepath.Base(r.Org) + "-serving-release-v1.0__412.yaml",
+					SourceSHA: shas["release-v1.0"],
+					Images:    []string{"knative-serving-controller"},
+					Tests:     []string{"test-e2e-aws-ocp-412", "test-e2e-aws-ocp-412-continuous"},
+				},
+				{
+					Path:      filepath.Base(r.Org) + "-serving-release-v1.1__411.yaml",
+					SourceSHA: shas["release-v1.1"],
+					Images:    []string{"knative-serving-controller", "knative-serving-webhook"},
+					Tests:     []string{"test-conformance-aws-ocp-411", "test-conformance-aws-ocp-411-continuous", "test-e2e-aws-ocp-411", "test-e2e-aws-ocp-411-continuous"},
+				},
+				{
+					Path:      filepath.Base(r.Org) + "-serving-release-v1.1__412.yaml",
+					SourceSHA: shas["release-v1.1"],
+					Images:    []string{"knative-serving-controller", "knative-serving-webhook"},
+					Tests:     []string{"test-conformance-aws-ocp-412", "test-conformance-aws-ocp-412-continuous", "test-e2e-aws-ocp-412", "test-e2e-aws-ocp-412-continuous"},
+				},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Unexpected configs (-want, +got): \n%s", diff)
+			}
+
+			assertNoCheckouts(t, r)
+		})
+	}
+}
+
+func TestNewGenerateConfigsUnresolvableBranch(t *testing.T) {
+	ctx := context.Background()
+
+	upstream := newTestGitRepository(t)
+	mustGit(t, upstream, "checkout", "-q", "-b", "release-v1.0")
+	mustWriteFile(t, upstream, "Makefile", "test-e2e:\n\techo v1.0\n")
+	mustGit(t, upstream, "add", ".")
+	mustGit(t, upstream, "commit", "-q", "-m", "release-v1.0")
+
+	previousRemoteURL, previousCacheDir := gitRemoteURL, CloneCacheDir
+	t.Cleanup(func() { gitRemoteURL, CloneCacheDir = previousRemoteURL, previousCacheDir })
+	gitRemoteURL = func(r Repository) string { return upstream.RepositoryDirectory() }
+	CloneCacheDir = ""
+
+	r := Repository{
+		Org:         t.TempDir(),
+		Repo:        "serving",
+		ImagePrefix: "knative-serving",
+		E2ETests: E2ETests{
+			Matches: []string{"test-.*"},
+		},
+	}
+	cc := CommonConfig{
+		Branches: map[string]Branch{
+			"release-v1.0":  {OpenShiftVersions: []string{"4.11", "4.12"}},
+			"release-v99.0": {OpenShiftVersions: []string{"4.11", "4.12"}},
+		},
+	}
+
+	var generated int32
+	countGenerated := func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
+		atomic.AddInt32(&generated, 1)
+		return nil
+	}
+
+	_, err := NewGenerateConfigs(ctx, r, cc, countGenerated)
+	if err == nil || !strings.Contains(err.Error(), "failed to resolve branch release-v99.0") {
+		t.Fatalf("expected error resolving branch release-v99.0, got %v", err)
+	}
+
+	// No configuration is generated when a branch doesn't resolve.
+	if n := atomic.LoadInt32(&generated); n != 0 {
+		t.Errorf("expected no configuration to be generated, got %d", n)
+	}
+
+	assertNoCheckouts(t, r)
+}
+
+// assertNoCheckouts fails when branches have been checked out next to the clone of r.
+func assertNoCheckouts(t *testing.T, r Repository) {
+	t.Helper()
+
+	entries, err := os.ReadDir(r.Org)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if diff := cmp.Diff([]string{r.Repo}, names); diff != "" {
+		t.Errorf("Unexpected directories in %s (-want, +got): \n%s", r.Org, diff)
+	}
+
+	out, err := runQuiet(context.Background(), r, "git", "worktree", "list", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(out), "worktree "); n != 1 {
+		t.Errorf("expected only the main worktree, got:\n%s", out)
+	}
+}
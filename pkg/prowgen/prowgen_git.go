@@ -10,11 +10,215 @@ import (
 	"strings"
 )
 
+const (
+	// ExecGitBackend runs git operations by shelling out to git.
+	ExecGitBackend = "exec"
+	// GoGitBackend runs read-only git operations in pure Go.
+	GoGitBackend = "go"
+)
+
+// GitReader is the interface for read-only git operations.
+//
+// Refs can be branch names, tags or commit SHAs, reads happen straight from the object store
+// without checking out refs in the working tree.
+type GitReader interface {
+	// Branches lists local branches.
+	Branches(ctx context.Context, r Repository) ([]string, error)
+	// RevParse resolves the given ref to a commit SHA.
+	RevParse(ctx context.Context, r Repository, ref string) (string, error)
+	// ReadFile reads the file at the given path at ref, it returns an error wrapping
+	// os.ErrNotExist when the file doesn't exist.
+	ReadFile(ctx context.Context, r Repository, ref string, path string) ([]byte, error)
+	// ListFiles lists files in the given directory, recursively, at ref.
+	ListFiles(ctx context.Context, r Repository, ref string, dir string) ([]string, error)
+	// DiffNames lists names of files changed between the two refs.
+	DiffNames(ctx context.Context, r Repository, from string, to string) ([]string, error)
+}
+
+// Git is the interface for git operations.
+type Git interface {
+	GitReader
+
+	Clone(ctx context.Context, r Repository) error
+	Checkout(ctx context.Context, r Repository, ref string) error
+	Fetch(ctx context.Context, r Repository, sha string) error
+	Merge(ctx context.Context, r Repository, sha string) error
+	DiffNameOnly(ctx context.Context, r Repository, sha string) ([]string, error)
+}
+
+// NewGitReader returns a GitReader for the given backend.
+func NewGitReader(backend string) (GitReader, error) {
+	switch backend {
+	case ExecGitBackend, "":
+		return ExecGit{}, nil
+	case GoGitBackend:
+		return GoGitReader{}, nil
+	default:
+		return nil, fmt.Errorf("unknown git backend %q, supported backends: [%s, %s]", backend, ExecGitBackend, GoGitBackend)
+	}
+}
+
+// DiscoveryGitReader is the GitReader discovery reads branch files with, see the --git-backend flag.
+var DiscoveryGitReader GitReader = ExecGit{}
+
+// readRepositoryFile reads the file at the given path, relative to the repository, at the ref of the
+// repository, or from its working directory when no ref is set.
+func readRepositoryFile(ctx context.Context, r Repository, path string) ([]byte, error) {
+	if r.ref == "" {
+		p := filepath.Join(r.RepositoryDirectory(), path)
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			return nil, fmt.Errorf("[%s] %s is a directory: %w", r.RepositoryDirectory(), path, os.ErrNotExist)
+		}
+		return os.ReadFile(p)
+	}
+	return DiscoveryGitReader.ReadFile(ctx, r, r.ref, path)
+}
+
+// listRepositoryFiles lists files in the given directory, recursively, relative to the repository,
+// at the ref of the repository, or from its working directory when no ref is set.
+func listRepositoryFiles(ctx context.Context, r Repository, dir string) ([]string, error) {
+	if r.ref != "" {
+		return DiscoveryGitReader.ListFiles(ctx, r, r.ref, dir)
+	}
+
+	var files []string
+	root := r.RepositoryDirectory()
+	err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to list files in %s: %w", r.RepositoryDirectory(), dir, err)
+	}
+	return files, nil
+}
+
+// ExecGit implements Git by shelling out to git.
+type ExecGit struct{}
+
+var _ Git = ExecGit{}
+
+func (ExecGit) Clone(ctx context.Context, r Repository) error {
+	return GitClone(ctx, r)
+}
+
+func (ExecGit) Checkout(ctx context.Context, r Repository, ref string) error {
+	return GitCheckout(ctx, r, ref)
+}
+
+func (ExecGit) Fetch(ctx context.Context, r Repository, sha string) error {
+	return GitFetch(ctx, r, sha)
+}
+
+func (ExecGit) Merge(ctx context.Context, r Repository, sha string) error {
+	return GitMerge(ctx, r, sha)
+}
+
+func (ExecGit) DiffNameOnly(ctx context.Context, r Repository, sha string) ([]string, error) {
+	return GitDiffNameOnly(ctx, r, sha)
+}
+
+func (ExecGit) Branches(ctx context.Context, r Repository) ([]string, error) {
+	out, err := runQuiet(ctx, r, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (ExecGit) RevParse(ctx context.Context, r Repository, ref string) (string, error) {
+	return GitRevParse(ctx, r, ref)
+}
+
+func (ExecGit) ReadFile(ctx context.Context, r Repository, ref string, path string) ([]byte, error) {
+	object := ref + ":" + filepath.ToSlash(path)
+	if t, err := runQuiet(ctx, r, "git", "cat-file", "-t", object); err != nil || strings.TrimSpace(string(t)) != "blob" {
+		return nil, fmt.Errorf("[%s] file %s not found at %s: %w", r.RepositoryDirectory(), path, ref, os.ErrNotExist)
+	}
+	return runQuiet(ctx, r, "git", "show", object)
+}
+
+func (ExecGit) ListFiles(ctx context.Context, r Repository, ref string, dir string) ([]string, error) {
+	out, err := runQuiet(ctx, r, "git", "ls-tree", "-r", "--name-only", ref, "--", filepath.ToSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (ExecGit) DiffNames(ctx context.Context, r Repository, from string, to string) ([]string, error) {
+	out, err := runQuiet(ctx, r, "git", "diff", "--name-only", from, to)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func splitLines(out []byte) []string {
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// CloneCacheDir is the directory holding mirror clones shared across runs, repositories are
+// cloned and fetched from the mirror clones, which are fetched incrementally on each run.
+//
+// Empty disables the cache.
+var CloneCacheDir string
+
+// gitRemoteURL returns the URL of the remote repository.
+var gitRemoteURL = func(r Repository) string {
+	return fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
+}
+
+// DefaultCloneCacheDir returns the default clone cache directory, for example,
+// $XDG_CACHE_HOME/openshift-knative-hack/git.
+func DefaultCloneCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openshift-knative-hack", "git")
+}
+
+// GitCheckout checks out the given ref discarding local changes and untracked files, so that the
+// working tree always reflects the ref, even when the ref has been updated by a fetch.
 func GitCheckout(ctx context.Context, r Repository, branch string) error {
-	_, err := run(ctx, r, "git", "checkout", branch)
+	if _, err := run(ctx, r, "git", "checkout", "-f", branch); err != nil {
+		return err
+	}
+	if _, err := run(ctx, r, "git", "reset", "--hard", branch); err != nil {
+		return err
+	}
+	_, err := run(ctx, r, "git", "clean", "-fd")
 	return err
 }
 
+// GitRevParse resolves the given ref to a commit SHA.
+func GitRevParse(ctx context.Context, r Repository, ref string) (string, error) {
+	out, err := runQuiet(ctx, r, "git", "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GitClone clones the given repository or, when it's already cloned, fetches it incrementally.
 func GitClone(ctx context.Context, r Repository) error {
 	select {
 	case <-ctx.Done():
@@ -22,12 +226,20 @@ func GitClone(ctx context.Context, r Repository) error {
 	default:
 	}
 
+	remoteRepo := gitRemoteURL(r)
+	if CloneCacheDir != "" {
+		mirror, err := gitCacheMirror(ctx, r, remoteRepo)
+		if err != nil {
+			return err
+		}
+		remoteRepo = mirror
+	}
+
 	if _, err := os.Stat(r.RepositoryDirectory()); !errors.Is(err, os.ErrNotExist) {
-		log.Println("Repository", r.RepositoryDirectory(), "already cloned")
-		return nil
+		log.Println("Repository", r.RepositoryDirectory(), "already cloned, fetching", remoteRepo)
+		return gitFetchBranches(ctx, r, remoteRepo)
 	}
 
-	remoteRepo := fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
 	localRepo := filepath.Join(r.RepositoryDirectory(), ".git")
 
 	if err := os.RemoveAll(r.RepositoryDirectory()); err != nil {
@@ -38,7 +250,8 @@ func GitClone(ctx context.Context, r Repository) error {
 		return fmt.Errorf("[%s] failed to create directory: %w", r.RepositoryDirectory(), err)
 	}
 
-	if _, err := runNoRepo(ctx, "git", "clone", "--mirror", remoteRepo, localRepo); err != nil {
+	removePartialClone := func() error { return os.RemoveAll(localRepo) }
+	if _, err := runGitNetwork(ctx, r, "", removePartialClone, "clone", "--mirror", remoteRepo, localRepo); err != nil {
 		return fmt.Errorf("[%s] failed to clone repository: %w", r.RepositoryDirectory(), err)
 	}
 
@@ -49,14 +262,85 @@ func GitClone(ctx context.Context, r Repository) error {
 	return nil
 }
 
+// gitCacheMirror creates or incrementally fetches the mirror clone of the given repository in
+// CloneCacheDir and returns its path.
+func gitCacheMirror(ctx context.Context, r Repository, remoteRepo string) (string, error) {
+	mirror, err := filepath.Abs(filepath.Join(CloneCacheDir, r.Org, r.Repo+".git"))
+	if err != nil {
+		return "", fmt.Errorf("[%s] failed to get cache directory: %w", r.RepositoryDirectory(), err)
+	}
+
+	if _, err := os.Stat(mirror); err == nil {
+		log.Println("Repository", r.RepositoryDirectory(), "found in cache", mirror, "fetching", remoteRepo)
+		if _, err := runGitNetwork(ctx, r, mirror, nil, "remote", "update", "--prune"); err != nil {
+			return "", fmt.Errorf("[%s] failed to fetch cached repository %s: %w", r.RepositoryDirectory(), mirror, err)
+		}
+		return mirror, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(mirror), os.ModePerm); err != nil {
+		return "", fmt.Errorf("[%s] failed to create cache directory: %w", r.RepositoryDirectory(), err)
+	}
+	removePartialClone := func() error { return os.RemoveAll(mirror) }
+	if _, err := runGitNetwork(ctx, r, "", removePartialClone, "clone", "--mirror", remoteRepo, mirror); err != nil {
+		_ = removePartialClone()
+		return "", fmt.Errorf("[%s] failed to clone repository in cache: %w", r.RepositoryDirectory(), err)
+	}
+	return mirror, nil
+}
+
+// gitFetchBranches fetches branches and tags of an existing clone and logs the branches that
+// were stale.
+func gitFetchBranches(ctx context.Context, r Repository, remoteRepo string) error {
+	before, err := gitBranchesSHAs(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	// --update-head-ok allows updating the checked out branch, GitCheckout updates the working tree.
+	_, err = runGitNetwork(ctx, r, r.RepositoryDirectory(), nil,
+		"fetch", "--prune", "--update-head-ok", remoteRepo,
+		"+refs/heads/*:refs/heads/*",
+		"+refs/tags/*:refs/tags/*",
+	)
+	if err != nil {
+		return fmt.Errorf("[%s] failed to fetch repository: %w", r.RepositoryDirectory(), err)
+	}
+
+	after, err := gitBranchesSHAs(ctx, r)
+	if err != nil {
+		return err
+	}
+	for branch, sha := range after {
+		if previous, ok := before[branch]; ok && previous != sha {
+			log.Println("Repository", r.RepositoryDirectory(), "branch", branch, "was stale, updated from", previous, "to", sha)
+		}
+	}
+	return nil
+}
+
+func gitBranchesSHAs(ctx context.Context, r Repository) (map[string]string, error) {
+	out, err := runQuiet(ctx, r, "git", "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	shas := make(map[string]string)
+	for _, l := range splitLines(out) {
+		if parts := strings.Fields(l); len(parts) == 2 {
+			shas[parts[0]] = parts[1]
+		}
+	}
+	return shas, nil
+}
+
 func GitMerge(ctx context.Context, r Repository, sha string) error {
-	_, err := run(ctx, r, "git", "merge", sha, "--no-ff", "-m", "Merge " + sha)
+	_, err := run(ctx, r, "git", "merge", sha, "--no-ff", "-m", "Merge "+sha)
 	return err
 }
 
 func GitFetch(ctx context.Context, r Repository, sha string) error {
-	remoteRepo := fmt.Sprintf("https://github.com/%s/%s.git", r.Org, r.Repo)
-	_, err := runNoRepo(ctx, "git", "fetch", remoteRepo, sha)
+	remoteRepo := gitRemoteURL(r)
+	_, err := runGitNetwork(ctx, r, r.RepositoryDirectory(), nil, "fetch", remoteRepo, sha)
 	return err
 }
 
@@ -65,5 +349,5 @@ func GitDiffNameOnly(ctx context.Context, r Repository, sha string) ([]string, e
 	if err != nil {
 		return nil, err
 	}
-	return strings.Split(strings.TrimSpace(string(out)), "\n"), nil
+	return splitLines(out), nil
 }
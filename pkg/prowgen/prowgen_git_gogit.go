@@ -0,0 +1,164 @@
+package prowgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// GoGitReader implements GitReader in pure Go, reading from the object store of an
+// existing clone.
+type GoGitReader struct{}
+
+var _ GitReader = GoGitReader{}
+
+func (GoGitReader) Branches(ctx context.Context, r Repository) ([]string, error) {
+	repo, err := openGoGitRepository(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to list branches: %w", r.RepositoryDirectory(), err)
+	}
+	var branches []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		branches = append(branches, ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to list branches: %w", r.RepositoryDirectory(), err)
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+func (GoGitReader) RevParse(ctx context.Context, r Repository, ref string) (string, error) {
+	repo, err := openGoGitRepository(ctx, r)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("[%s] failed to resolve %s: %w", r.RepositoryDirectory(), ref, err)
+	}
+	return hash.String(), nil
+}
+
+func (GoGitReader) ReadFile(ctx context.Context, r Repository, ref string, path string) ([]byte, error) {
+	tree, err := goGitTree(ctx, r, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, err := tree.FindEntry(filepath.ToSlash(path)); err == nil && !e.Mode.IsFile() {
+		return nil, fmt.Errorf("[%s] file %s not found at %s: %w", r.RepositoryDirectory(), path, ref, os.ErrNotExist)
+	}
+	f, err := tree.File(filepath.ToSlash(path))
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, fmt.Errorf("[%s] file %s not found at %s: %w", r.RepositoryDirectory(), path, ref, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("[%s] failed to read file %s at %s: %w", r.RepositoryDirectory(), path, ref, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to read file %s at %s: %w", r.RepositoryDirectory(), path, ref, err)
+	}
+	return []byte(content), nil
+}
+
+func (GoGitReader) ListFiles(ctx context.Context, r Repository, ref string, dir string) ([]string, error) {
+	tree, err := goGitTree(ctx, r, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(filepath.ToSlash(dir), "/") + "/"
+	if dir == "" || dir == "." {
+		prefix = ""
+	}
+
+	var files []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if strings.HasPrefix(f.Name, prefix) {
+			files = append(files, f.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to list files in %s at %s: %w", r.RepositoryDirectory(), dir, ref, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (GoGitReader) DiffNames(ctx context.Context, r Repository, from string, to string) ([]string, error) {
+	fromTree, err := goGitTree(ctx, r, from)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := goGitTree(ctx, r, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(ctx, fromTree, toTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to diff %s and %s: %w", r.RepositoryDirectory(), from, to, err)
+	}
+
+	names := sets.NewString()
+	for _, c := range changes {
+		if c.From.Name != "" {
+			names.Insert(c.From.Name)
+		}
+		if c.To.Name != "" {
+			names.Insert(c.To.Name)
+		}
+	}
+	return names.List(), nil
+}
+
+func openGoGitRepository(ctx context.Context, r Repository) (*git.Repository, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpen(r.RepositoryDirectory())
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to open repository: %w", r.RepositoryDirectory(), err)
+	}
+	return repo, nil
+}
+
+func goGitTree(ctx context.Context, r Repository, ref string) (*object.Tree, error) {
+	repo, err := openGoGitRepository(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to resolve %s: %w", r.RepositoryDirectory(), ref, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to get commit %s: %w", r.RepositoryDirectory(), ref, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to get tree of %s: %w", r.RepositoryDirectory(), ref, err)
+	}
+	return tree, nil
+}
@@ -0,0 +1,248 @@
+package prowgen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestGitReaders(t *testing.T) {
+	ctx := context.Background()
+	r := newTestGitRepository(t)
+
+	mustGit(t, r, "checkout", "-q", "-b", "main")
+	mustWriteFile(t, r, "Makefile", "test-e2e:\n\techo main\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-images/dispatcher/Dockerfile", "FROM scratch\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "main")
+
+	mustGit(t, r, "checkout", "-q", "-b", "release-next")
+	mustWriteFile(t, r, "Makefile", "test-e2e:\n\techo release-next\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-test-images/webhook/Dockerfile", "FROM scratch\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "release-next")
+
+	// Reads must not depend on the checked out branch.
+	mustGit(t, r, "checkout", "-q", "main")
+
+	for _, backend := range []string{ExecGitBackend, GoGitBackend} {
+		t.Run(backend, func(t *testing.T) {
+			reader, err := NewGitReader(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			branches, err := reader.Branches(ctx, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"main", "release-next"}, branches); diff != "" {
+				t.Errorf("Unexpected branches (-want, +got): \n%s", diff)
+			}
+
+			makefile, err := reader.ReadFile(ctx, r, "release-next", "Makefile")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff("test-e2e:\n\techo release-next\n", string(makefile)); diff != "" {
+				t.Errorf("Unexpected Makefile (-want, +got): \n%s", diff)
+			}
+
+			if _, err := reader.ReadFile(ctx, r, "main", "openshift/ci-operator/knative-test-images/webhook/Dockerfile"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected not exist error, got %v", err)
+			}
+
+			files, err := reader.ListFiles(ctx, r, "release-next", "openshift/ci-operator")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedFiles := []string{
+				"openshift/ci-operator/knative-images/dispatcher/Dockerfile",
+				"openshift/ci-operator/knative-test-images/webhook/Dockerfile",
+			}
+			if diff := cmp.Diff(expectedFiles, files); diff != "" {
+				t.Errorf("Unexpected files (-want, +got): \n%s", diff)
+			}
+
+			names, err := reader.DiffNames(ctx, r, "main", "release-next")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedNames := []string{
+				"Makefile",
+				"openshift/ci-operator/knative-test-images/webhook/Dockerfile",
+			}
+			if diff := cmp.Diff(expectedNames, names); diff != "" {
+				t.Errorf("Unexpected diff names (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoveryGitBackends(t *testing.T) {
+	r := newTestGitRepository(t)
+	r.E2ETests.Matches = []string{"test-e2e$"}
+
+	mustGit(t, r, "checkout", "-q", "-b", "main")
+	mustWriteFile(t, r, "Makefile", "test-unit:\n\techo main\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-images/dispatcher/Dockerfile", "FROM scratch\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "main")
+
+	mustGit(t, r, "checkout", "-q", "-b", "release-next")
+	mustWriteFile(t, r, "Makefile", "test-e2e:\n\techo release-next\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-images/webhook/Dockerfile", "FROM registry.ci.openshift.org/openshift/release:golang-1.18\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "release-next")
+
+	// Discovery must read files at the ref, not from the checked out branch.
+	mustGit(t, r, "checkout", "-q", "main")
+	r.ref = "release-next"
+
+	previousReader := DiscoveryGitReader
+	t.Cleanup(func() { DiscoveryGitReader = previousReader })
+
+	for _, backend := range []string{ExecGitBackend, GoGitBackend} {
+		t.Run(backend, func(t *testing.T) {
+			reader, err := NewGitReader(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			DiscoveryGitReader = reader
+
+			tests, err := discoverE2ETests(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedTests := []Test{{Command: "test-e2e", Recipe: []string{"echo release-next"}}}
+			if diff := cmp.Diff(expectedTests, tests); diff != "" {
+				t.Errorf("Unexpected tests (-want, +got): \n%s", diff)
+			}
+
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			if err := applyOptions(&cfg, DiscoverImages(r)); err != nil {
+				t.Fatal(err)
+			}
+			var dockerfiles []string
+			for _, img := range cfg.Images {
+				dockerfiles = append(dockerfiles, img.DockerfilePath)
+			}
+			expectedDockerfiles := []string{
+				"openshift/ci-operator/knative-images/dispatcher/Dockerfile",
+				"openshift/ci-operator/knative-images/webhook/Dockerfile",
+			}
+			if diff := cmp.Diff(expectedDockerfiles, dockerfiles); diff != "" {
+				t.Errorf("Unexpected Dockerfiles (-want, +got): \n%s", diff)
+			}
+			if _, ok := cfg.BaseImages["openshift_release_golang-1.18"]; !ok {
+				t.Errorf("expected base image read from the Dockerfile at %s, got %v", r.ref, cfg.BaseImages)
+			}
+		})
+	}
+
+	if _, err := NewGitReader("svn"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func newTestGitRepository(t *testing.T) Repository {
+	r := Repository{Org: t.TempDir(), Repo: "repo"}
+	if err := os.MkdirAll(r.RepositoryDirectory(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	mustGit(t, r, "init", "-q")
+	mustGit(t, r, "config", "user.email", "test@example.com")
+	mustGit(t, r, "config", "user.name", "test")
+	mustGit(t, r, "config", "commit.gpgsign", "false")
+	return r
+}
+
+func mustGit(t *testing.T, r Repository, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.RepositoryDirectory()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+func mustWriteFile(t *testing.T, r Repository, path string, content string) {
+	t.Helper()
+	path = filepath.Join(r.RepositoryDirectory(), path)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitCloneCache(t *testing.T) {
+	ctx := context.Background()
+
+	upstream := newTestGitRepository(t)
+	mustGit(t, upstream, "checkout", "-q", "-b", "main")
+	mustWriteFile(t, upstream, "Makefile", "test-e2e:\n\techo v1\n")
+	mustGit(t, upstream, "add", ".")
+	mustGit(t, upstream, "commit", "-q", "-m", "v1")
+
+	previousRemoteURL, previousCacheDir := gitRemoteURL, CloneCacheDir
+	t.Cleanup(func() { gitRemoteURL, CloneCacheDir = previousRemoteURL, previousCacheDir })
+	gitRemoteURL = func(r Repository) string { return upstream.RepositoryDirectory() }
+	CloneCacheDir = t.TempDir()
+
+	r := Repository{Org: t.TempDir(), Repo: "repo"}
+
+	cloneAndCheckout := func() string {
+		if err := GitClone(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+		if err := GitCheckout(ctx, r, "main"); err != nil {
+			t.Fatal(err)
+		}
+		sha, err := GitRevParse(ctx, r, "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sha
+	}
+
+	upstreamSHA, err := GitRevParse(ctx, upstream, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cloneAndCheckout(); got != upstreamSHA {
+		t.Errorf("Want SHA %s, got %s", upstreamSHA, got)
+	}
+
+	// Update upstream, a second run must not generate from a stale checkout.
+	mustWriteFile(t, upstream, "Makefile", "test-e2e:\n\techo v2\n")
+	mustGit(t, upstream, "commit", "-q", "-a", "-m", "v2")
+	upstreamSHA, err = GitRevParse(ctx, upstream, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cloneAndCheckout(); got != upstreamSHA {
+		t.Errorf("Want SHA %s, got %s", upstreamSHA, got)
+	}
+	makefile, err := os.ReadFile(filepath.Join(r.RepositoryDirectory(), "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("test-e2e:\n\techo v2\n", string(makefile)); diff != "" {
+		t.Errorf("Unexpected Makefile (-want, +got): \n%s", diff)
+	}
+
+	cached, err := os.Stat(filepath.Join(CloneCacheDir, r.Org, r.Repo+".git"))
+	if err != nil || !cached.IsDir() {
+		t.Errorf("expected repository to be cached: %v", err)
+	}
+}
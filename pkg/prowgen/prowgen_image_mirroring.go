@@ -64,21 +64,31 @@ func GenerateImageMirroringConfigs(openshiftRelease Repository, cfgs []ReleaseBu
 	return mirroringConfigs
 }
 
-func ReconcileImageMirroringConfig(mirroring ImageMirroringConfig) error {
-	matching := filepath.Join(filepath.Dir(mirroring.Path), "*"+mirroring.Release+"_"+mirroring.Metadata.Repo+QuayMirroringSuffix)
-	existing, err := filepath.Glob(matching)
-	if err != nil {
-		return fmt.Errorf("failed to find files matching %s: %w", matching, err)
-	}
+// ReconcileImageMirroringConfig writes the given image mirroring configuration, adds it to the
+// manifest and returns the deleted files.
+//
+// Previously generated configurations are pruned using the manifest, when the manifest is missing,
+// configurations for the same release and repository are deleted instead.
+func ReconcileImageMirroringConfig(mirroring ImageMirroringConfig, manifest *Manifest) ([]string, error) {
+	var deleted []string
+	if manifest.Missing() {
+		matching := filepath.Join(filepath.Dir(mirroring.Path), "*"+mirroring.Release+"_"+mirroring.Metadata.Repo+QuayMirroringSuffix)
+		existing, err := filepath.Glob(matching)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find files matching %s: %w", matching, err)
+		}
 
-	for _, f := range existing {
-		if err := os.Remove(f); err != nil {
-			return fmt.Errorf("failed to delete file %s: %w", f, err)
+		for _, f := range existing {
+			if err := os.Remove(f); err != nil {
+				return nil, fmt.Errorf("failed to delete file %s: %w", f, err)
+			}
+			deleted = append(deleted, f)
 		}
 	}
 
 	if err := os.WriteFile(mirroring.Path, []byte(mirroring.Content), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", mirroring.Path, err)
+		return nil, fmt.Errorf("failed to write file %s: %w", mirroring.Path, err)
 	}
-	return nil
+	manifest.Add(mirroring.Path)
+	return deleted, nil
 }
@@ -1,9 +1,6 @@
 package prowgen
 
 import (
-	"path/filepath"
-	"strings"
-
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 )
 
@@ -23,13 +20,7 @@ type ImageInput struct {
 func ProjectDirectoryImageBuildStepConfigurationFuncFromImageInput(r Repository, input ImageInput) ProjectDirectoryImageBuildStepConfigurationFunc {
 	return func() (cioperatorapi.ProjectDirectoryImageBuildStepConfiguration, error) {
 
-		context := ""
-		if input.Context != "" {
-			context = "-" + string(input.Context)
-		}
-
-		to := r.ImagePrefix + context + "-" + filepath.Base(filepath.Dir(input.DockerfilePath))
-		to = strings.ReplaceAll(to, "_", "-")
+		to := DefaultNamingPolicy.DockerfileImageName(r.ImagePrefix, input.Context, input.DockerfilePath)
 
 		return cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
 			To: cioperatorapi.PipelineImageStreamTagReference(to),
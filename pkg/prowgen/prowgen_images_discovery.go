@@ -2,9 +2,10 @@ package prowgen
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -39,7 +40,9 @@ func DiscoverImages(r Repository) ReleaseBuildConfigurationOption {
 }
 
 func discoverImages(r Repository) ([]ReleaseBuildConfigurationOption, error) {
-	dockerfiles, err := discoverDockerfiles(r)
+	ctx := context.Background()
+
+	dockerfiles, err := discoverDockerfiles(ctx, r)
 	if err != nil {
 		return nil, err
 	}
@@ -47,19 +50,28 @@ func discoverImages(r Repository) ([]ReleaseBuildConfigurationOption, error) {
 
 	log.Println(r.RepositoryDirectory(), "Discovered Dockerfiles", dockerfiles)
 
+	if err := dockerfileImageNameCollisions(r, dockerfiles); err != nil {
+		return nil, err
+	}
+
 	options := make([]ReleaseBuildConfigurationOption, 0, len(dockerfiles))
 
 	for _, dockerfile := range dockerfiles {
-		requiredBaseImages, inputImages, err := discoverInputImages(dockerfile)
+		requiredBaseImages, inputImages, err := discoverInputImages(ctx, r, dockerfile)
 		if err != nil {
 			return nil, err
 		}
 
+		dockerfilePath, err := filepath.Rel(r.RepositoryDirectory(), dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] failed to get relative path of %s: %w", r.RepositoryDirectory(), dockerfile, err)
+		}
+
 		options = append(options,
 			WithBaseImages(requiredBaseImages),
 			WithImage(ProjectDirectoryImageBuildStepConfigurationFuncFromImageInput(r, ImageInput{
-				Context:        discoverImageContext(dockerfile),
-				DockerfilePath: strings.Join(strings.Split(dockerfile, string(os.PathSeparator))[2:], string(os.PathSeparator)),
+				Context:        discoverImageContext(dockerfilePath),
+				DockerfilePath: dockerfilePath,
 				Inputs:         inputImages,
 			})),
 		)
@@ -68,6 +80,24 @@ func discoverImages(r Repository) ([]ReleaseBuildConfigurationOption, error) {
 	return options, nil
 }
 
+// dockerfileImageNameCollisions returns an error when Dockerfiles in different directories, such as
+// knative-images/controller and other-images/controller, produce the same image name.
+func dockerfileImageNameCollisions(r Repository, dockerfiles []string) error {
+	sources := make(map[string][]string, len(dockerfiles))
+	for _, dockerfile := range dockerfiles {
+		dockerfilePath, err := filepath.Rel(r.RepositoryDirectory(), dockerfile)
+		if err != nil {
+			return fmt.Errorf("[%s] failed to get relative path of %s: %w", r.RepositoryDirectory(), dockerfile, err)
+		}
+		name := DefaultNamingPolicy.DockerfileImageName(r.ImagePrefix, discoverImageContext(dockerfilePath), dockerfilePath)
+		sources[name] = append(sources[name], dockerfilePath)
+	}
+	if err := ImageNameCollisions(sources); err != nil {
+		return fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+	}
+	return nil
+}
+
 func discoverImageContext(dockerfile string) imageContext {
 	context := ProductionContext
 	if strings.Contains(dockerfile, "test-images") {
@@ -76,17 +106,24 @@ func discoverImageContext(dockerfile string) imageContext {
 	return context
 }
 
-func discoverDockerfiles(r Repository) ([]string, error) {
-	dir := filepath.Join(r.RepositoryDirectory(), "openshift", "ci-operator")
-	dockerfiles, err := filepath.Glob(filepath.Join(dir, "**", "**", "Dockerfile"))
+func discoverDockerfiles(ctx context.Context, r Repository) ([]string, error) {
+	dir := filepath.Join("openshift", "ci-operator")
+	files, err := listRepositoryFiles(ctx, r, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed while discovering container images in %s: %w", dir, err)
 	}
+
+	var dockerfiles []string
+	for _, f := range files {
+		if ok, _ := filepath.Match(filepath.Join(dir, "*", "*", "Dockerfile"), filepath.FromSlash(f)); ok {
+			dockerfiles = append(dockerfiles, filepath.Join(r.RepositoryDirectory(), f))
+		}
+	}
 	return dockerfiles, nil
 }
 
-func discoverInputImages(dockerfile string) (map[string]cioperatorapi.ImageStreamTagReference, map[string]cioperatorapi.ImageBuildInputs, error) {
-	imagePaths, err := getPullStringsFromDockerfile(dockerfile)
+func discoverInputImages(ctx context.Context, r Repository, dockerfile string) (map[string]cioperatorapi.ImageStreamTagReference, map[string]cioperatorapi.ImageBuildInputs, error) {
+	imagePaths, err := getPullStringsFromDockerfile(ctx, r, dockerfile)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not get pull images from dockerfile: %w", err)
 	}
@@ -114,15 +151,18 @@ func discoverInputImages(dockerfile string) (map[string]cioperatorapi.ImageStrea
 	return requiredBaseImages, inputImages, nil
 }
 
-func getPullStringsFromDockerfile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
+func getPullStringsFromDockerfile(ctx context.Context, r Repository, filename string) ([]string, error) {
+	path, err := filepath.Rel(r.RepositoryDirectory(), filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relative path of %s: %w", filename, err)
+	}
+	content, err := readRepositoryFile(ctx, r, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Dockerfile %s: %w", filename, err)
 	}
-	defer file.Close()
 
 	var images []string
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
 	for scanner.Scan() {
 		line := scanner.Text()
 		if !strings.Contains(line, "FROM ") {
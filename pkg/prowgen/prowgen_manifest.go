@@ -0,0 +1,141 @@
+package prowgen
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const manifestHeader = "# Files generated by prowgen, DO NOT EDIT.\n"
+
+// Manifest lists the files prowgen generated for a config file.
+//
+// prowgen only prunes files it owns, which are files listed in the manifest of the previous run,
+// and, for configured branches, files matching the exact name of generated configurations.
+type Manifest struct {
+	Files []string `json:"files" yaml:"files"`
+
+	// missing is true when the manifest file didn't exist, see Missing.
+	missing bool
+	lock    sync.Mutex
+}
+
+// DefaultManifestPath returns the default manifest path for the given config file, for example,
+// config/manifests/eventing.yaml for config/eventing.yaml.
+func DefaultManifestPath(config string) string {
+	return filepath.Join(filepath.Dir(config), "manifests", filepath.Base(config))
+}
+
+// ReadManifest reads the manifest at the given path, it returns an empty manifest when the file
+// doesn't exist.
+func ReadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{missing: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(content, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Missing returns true when the manifest file didn't exist, which is the case for the first run
+// after enabling the manifest, files generated by previous runs aren't tracked yet.
+func (m *Manifest) Missing() bool {
+	return m.missing
+}
+
+// Add adds the given generated files to the manifest.
+func (m *Manifest) Add(files ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Files = sets.NewString(m.Files...).Insert(files...).List()
+}
+
+// Write writes the manifest to the given path.
+func (m *Manifest) Write(path string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Files = sets.NewString(m.Files...).List()
+	out, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	if err := os.WriteFile(path, append([]byte(manifestHeader), out...), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// Prune deletes files listed in the manifest, including files generated for branches and
+// repositories that have been removed from the config, and returns the deleted files.
+func (m *Manifest) Prune() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleted := make([]string, 0, len(m.Files))
+	for _, f := range m.Files {
+		log.Println("Removing previously generated file", f)
+		if err := os.Remove(f); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to remove file %s: %w", f, err)
+		}
+		deleted = append(deleted, f)
+	}
+	m.Files = nil
+	return deleted, nil
+}
+
+// generatedReleaseBuildConfigurationRegex matches the exact names of configurations generated
+// for the given repository and branch, <org>-<repo>-<branch>__<variant>.yaml, where the variant
+// is an OpenShift version without dots.
+func generatedReleaseBuildConfigurationRegex(r Repository, branch string) *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(r.Org+"-"+r.Repo+"-"+branch) + `__[0-9]+\.yaml$`)
+}
+
+// pruneGeneratedReleaseBuildConfigurations deletes configurations generated for the given
+// repository and branch in outConfig and returns the deleted files.
+func pruneGeneratedReleaseBuildConfigurations(outConfig string, r Repository, branch string) ([]string, error) {
+	dir := filepath.Join(outConfig, r.RepositoryDirectory())
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	var deleted []string
+
+	generated := generatedReleaseBuildConfigurationRegex(r, branch)
+	for _, e := range entries {
+		if e.IsDir() || !generated.MatchString(e.Name()) {
+			continue
+		}
+		match := filepath.Join(dir, e.Name())
+		log.Println("Detected a new config for branch", branch, "removing file", match)
+		if err := os.Remove(match); err != nil {
+			return nil, fmt.Errorf("failed to remove file %s: %w", match, err)
+		}
+		deleted = append(deleted, match)
+	}
+	return deleted, nil
+}
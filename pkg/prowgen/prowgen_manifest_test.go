@@ -0,0 +1,215 @@
+package prowgen
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifests", "config.yaml")
+
+	m, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 0 {
+		t.Fatalf("expected empty manifest, got %v", m.Files)
+	}
+
+	removedBranch := filepath.Join(dir, "openshift-knative-eventing-release-v1.0__411.yaml")
+	mirroring := filepath.Join(dir, "knative-eventing_release-v1.0_eventing_quay")
+	for _, f := range []string{removedBranch, mirroring} {
+		if err := os.WriteFile(f, []byte("generated"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.Add(mirroring, removedBranch, mirroring)
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{mirroring, removedBranch}, m.Files); diff != "" {
+		t.Fatal("(-want, +got)", diff)
+	}
+
+	deleted, err := m.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{mirroring, removedBranch}, deleted); diff != "" {
+		t.Fatal("(-want, +got)", diff)
+	}
+	for _, f := range []string{removedBranch, mirroring} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be pruned, got %v", f, err)
+		}
+	}
+	// Pruning files that have been deleted by hand is not an error.
+	m.Add(removedBranch)
+	deleted, err = m.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("expected no deleted files, got %v", deleted)
+	}
+}
+
+func TestPruneGeneratedReleaseBuildConfigurations(t *testing.T) {
+	outConfig := t.TempDir()
+	r := Repository{Org: "openshift-knative", Repo: "eventing"}
+
+	dir := filepath.Join(outConfig, r.RepositoryDirectory())
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		"openshift-knative-eventing-release-v1.1__411.yaml",
+		"openshift-knative-eventing-release-v1.1__412.yaml",
+		"openshift-knative-eventing-release-v1.10__411.yaml",
+		"openshift-knative-eventing-release-v1.1__custom.yaml",
+		"openshift-knative-eventing-release-v1.1.yaml",
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("cfg"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := pruneGeneratedReleaseBuildConfigurations(outConfig, r, "release-v1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDeleted := []string{
+		filepath.Join(dir, "openshift-knative-eventing-release-v1.1__411.yaml"),
+		filepath.Join(dir, "openshift-knative-eventing-release-v1.1__412.yaml"),
+	}
+	if diff := cmp.Diff(wantDeleted, deleted); diff != "" {
+		t.Fatal("(-want, +got)", diff)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"openshift-knative-eventing-release-v1.1.yaml",
+		"openshift-knative-eventing-release-v1.10__411.yaml",
+		"openshift-knative-eventing-release-v1.1__custom.yaml",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("(-want, +got)", diff)
+	}
+
+	// Repositories without configurations are ignored.
+	if _, err := pruneGeneratedReleaseBuildConfigurations(outConfig, Repository{Org: "openshift-knative", Repo: "serving"}, "release-v1.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileImageMirroringConfig(t *testing.T) {
+	dir := t.TempDir()
+	mirroring := ImageMirroringConfig{
+		Path:     filepath.Join(dir, "mapping_knative_knative-v1.1_eventing_quay"),
+		Content:  "generated\n",
+		Release:  "knative-v1.1",
+		Metadata: cioperatorapi.Metadata{Repo: "eventing"},
+	}
+	stale := filepath.Join(dir, "mapping_knative-eventing_knative-v1.1_eventing_quay")
+	other := filepath.Join(dir, "mapping_knative_knative-v1.10_eventing_quay")
+
+	tests := []struct {
+		name        string
+		manifest    func(t *testing.T) *Manifest
+		wantDeleted []string
+		wantFiles   []string
+	}{
+		{
+			// Without a manifest, files generated by previous runs aren't tracked, fall back to
+			// deleting configurations for the same release and repository.
+			name: "missing manifest",
+			manifest: func(t *testing.T) *Manifest {
+				m, err := ReadManifest(filepath.Join(t.TempDir(), "config.yaml"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return m
+			},
+			wantDeleted: []string{stale},
+			wantFiles:   []string{"mapping_knative_knative-v1.10_eventing_quay", "mapping_knative_knative-v1.1_eventing_quay"},
+		},
+		{
+			// Stale files are pruned using the existing manifest.
+			name: "existing manifest",
+			manifest: func(t *testing.T) *Manifest {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := (&Manifest{}).Write(path); err != nil {
+					t.Fatal(err)
+				}
+				m, err := ReadManifest(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return m
+			},
+			wantFiles: []string{"mapping_knative-eventing_knative-v1.1_eventing_quay", "mapping_knative_knative-v1.10_eventing_quay", "mapping_knative_knative-v1.1_eventing_quay"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, f := range []string{stale, other} {
+				if err := os.WriteFile(f, []byte("generated"), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Cleanup(func() {
+				for _, f := range []string{stale, other, mirroring.Path} {
+					_ = os.Remove(f)
+				}
+			})
+
+			m := tt.manifest(t)
+			deleted, err := ReconcileImageMirroringConfig(mirroring, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantDeleted, deleted); diff != "" {
+				t.Error("(-want, +got)", diff)
+			}
+			if diff := cmp.Diff([]string{mirroring.Path}, m.Files); diff != "" {
+				t.Error("(-want, +got)", diff)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(entries))
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.wantFiles, got); diff != "" {
+				t.Error("(-want, +got)", diff)
+			}
+		})
+	}
+}
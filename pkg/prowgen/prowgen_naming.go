@@ -0,0 +1,217 @@
+package prowgen
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// maxNameLength is the maximum length for `As` for cluster claim-based tests
+	maxNameLength = 42
+	// maxJobNameLength is the maximum length for Prow job names, Prow stores job artifacts in
+	// GCS under logs/<job name>/<build id>, so a job name is a single path element, which is
+	// limited to 255 characters.
+	maxJobNameLength = 255
+
+	shaLength = 7
+
+	continuousSuffix = "-continuous"
+)
+
+// NamingPolicy defines how names of generated tests, jobs and images are derived and which
+// constraints they have to satisfy.
+type NamingPolicy struct {
+	// MaxTestNameLength is the maximum length of a test name, including the continuous suffix.
+	MaxTestNameLength int
+	// MaxJobNameLength is the maximum length of a Prow job name.
+	MaxJobNameLength int
+	// HashLength is the length of the hash appended to truncated names.
+	HashLength int
+}
+
+// DefaultNamingPolicy is the naming policy shared by every generator.
+var DefaultNamingPolicy = NamingPolicy{
+	MaxTestNameLength: maxNameLength,
+	MaxJobNameLength:  maxJobNameLength,
+	HashLength:        shaLength,
+}
+
+// Hash returns the hex encoded SHA-1 of s truncated to HashLength characters.
+func (p NamingPolicy) Hash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])[:p.HashLength]
+}
+
+// Truncate returns name when it isn't longer than maxLength, otherwise it returns a prefix of name
+// followed by the hash of the full name, which guarantees uniqueness.
+func (p NamingPolicy) Truncate(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+	prefix := name[:maxLength-p.HashLength-1]
+	// OpenShift CI doesnt' like double dashes, such as `stable-latest-test-kafka--7465737-aws-ocp-412`.
+	// So, if the prefix ends with a dash, we remove it.
+	prefix = strings.TrimRight(prefix, "-")
+	return prefix + "-" + p.Hash(name)
+}
+
+// TestName creates a test name for the given command following the constraints in openshift/release.
+// - name cannot be longer than MaxTestNameLength characters, including the continuous suffix.
+func (p NamingPolicy) TestName(command string, openShiftVersion string) string {
+	variant := strings.ReplaceAll(openShiftVersion, ".", "")
+	suffix := fmt.Sprintf("-aws-ocp-%s", variant)
+
+	return p.Truncate(command, p.MaxTestNameLength-len(suffix)-len(continuousSuffix)) + suffix
+}
+
+// ImageName creates an image name for the given prefix, context and name.
+//
+// Image names are never truncated, since they are referenced by the image env variables of
+// tests and by the image mirroring configurations.
+func (p NamingPolicy) ImageName(prefix string, context imageContext, name string) string {
+	c := ""
+	if context != "" {
+		c = "-" + string(context)
+	}
+	return strings.ReplaceAll(prefix+c+"-"+name, "_", "-")
+}
+
+// ImageNamingScheme defines how the name of the image of a main package is derived from the main
+// package path.
+type ImageNamingScheme string
+
+const (
+	// BaseImageNaming names images after the base name of the main package path, for example,
+	// controller for cmd/kafka/controller.
+	BaseImageNaming ImageNamingScheme = "base"
+	// PathImageNaming names images after the main package path relative to the closest cmd or
+	// test_images directory, for example, kafka-controller for cmd/kafka/controller.
+	PathImageNaming ImageNamingScheme = "path"
+)
+
+// MainPackageImageName returns the name, without prefix and context, of the image of the given
+// main package path, such as cmd/kafka/controller, according to the naming scheme, the default
+// scheme is BaseImageNaming.
+//
+// Generated Dockerfiles are written to a directory with this name, so that DockerfileImageName
+// derives the same image name.
+func (p NamingPolicy) MainPackageImageName(scheme ImageNamingScheme, mainPackage string) (string, error) {
+	elements := strings.Split(filepath.ToSlash(filepath.Clean(mainPackage)), "/")
+	switch scheme {
+	case "", BaseImageNaming:
+		return elements[len(elements)-1], nil
+	case PathImageNaming:
+		for i := len(elements) - 2; i >= 0; i-- {
+			if elements[i] == "cmd" || elements[i] == "test_images" {
+				elements = elements[i+1:]
+				break
+			}
+		}
+		if elements[0] == "vendor" {
+			elements = elements[1:]
+		}
+		return strings.Join(elements, "-"), nil
+	default:
+		return "", fmt.Errorf("unknown image naming scheme %q, supported schemes: %s, %s", scheme, BaseImageNaming, PathImageNaming)
+	}
+}
+
+// DockerfileImageName returns the image name of the given Dockerfile path, which is named after the
+// directory containing the Dockerfile.
+func (p NamingPolicy) DockerfileImageName(prefix string, context imageContext, dockerfilePath string) string {
+	return p.ImageName(prefix, context, filepath.Base(filepath.Dir(dockerfilePath)))
+}
+
+// ImageNameCollisions returns an error listing the image names derived from multiple sources, such
+// as Dockerfiles or main packages, given the sources of each image name.
+func ImageNameCollisions(sources map[string][]string) error {
+	var errs []string
+	for name, s := range sources {
+		if len(s) > 1 {
+			s = append([]string(nil), s...)
+			sort.Strings(s)
+			errs = append(errs, fmt.Sprintf("image name %s collides for %s", name, strings.Join(s, ", ")))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("image name collisions:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// Validate verifies that the names in the given configurations satisfy the policy constraints
+// and that they don't collide:
+// - test names are unique in a configuration,
+// - image names are unique in a configuration,
+// - job names are unique across configurations, including continuous and multi-version variants.
+func (p NamingPolicy) Validate(cfgs []ReleaseBuildConfiguration) error {
+	jobs := make(map[string]string)
+	var errs []string
+
+	for _, cfg := range cfgs {
+		tests := make(map[string]struct{}, len(cfg.Tests))
+		for _, test := range cfg.Tests {
+			if _, ok := tests[test.As]; ok {
+				errs = append(errs, fmt.Sprintf("%s: duplicate test name %s", cfg.Path, test.As))
+			}
+			tests[test.As] = struct{}{}
+
+			if test.ClusterClaim != nil && len(test.As) > p.MaxTestNameLength {
+				errs = append(errs, fmt.Sprintf("%s: test name %s is longer than %d characters", cfg.Path, test.As, p.MaxTestNameLength))
+			}
+
+			for _, job := range jobNames(cfg.Metadata, test) {
+				if len(job) > p.MaxJobNameLength {
+					errs = append(errs, fmt.Sprintf("%s: job name %s is longer than %d characters", cfg.Path, job, p.MaxJobNameLength))
+				}
+				if other, ok := jobs[job]; ok {
+					errs = append(errs, fmt.Sprintf("%s: job name %s collides with a job in %s", cfg.Path, job, other))
+				}
+				jobs[job] = cfg.Path
+			}
+		}
+
+		images := make(map[cioperatorapi.PipelineImageStreamTagReference]string, len(cfg.Images))
+		for _, img := range cfg.Images {
+			if other, ok := images[img.To]; ok {
+				errs = append(errs, fmt.Sprintf("%s: image name %s for %s collides with image for %s", cfg.Path, img.To, img.DockerfilePath, other))
+			}
+			images[img.To] = img.DockerfilePath
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("invalid names:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// jobNames returns the names of the Prow jobs openshift/release generates for the given test.
+func jobNames(metadata cioperatorapi.Metadata, test cioperatorapi.TestStepConfiguration) []string {
+	if test.Cron != nil || test.Interval != nil {
+		return []string{metadata.JobName("periodic", test.As)}
+	}
+	if test.Postsubmit {
+		return []string{metadata.JobName("branch", test.As)}
+	}
+	return []string{metadata.JobName("pull", test.As)}
+}
+
+// ToName creates a test name for the given Test following the constraints in openshift/release.
+// - name cannot be longer than maxNameLength characters.
+func ToName(r Repository, test *Test, openShiftVersion string) string {
+	name := DefaultNamingPolicy.TestName(test.Command, openShiftVersion)
+	if !strings.HasPrefix(name, test.Command+"-") {
+		log.Println(r.RepositoryDirectory(), "command as test name is too long", test.Command, "truncating it to", name)
+	}
+	return name
+}
@@ -0,0 +1,338 @@
+package prowgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestToName(t *testing.T) {
+
+	openshiftVersion := "4.11"
+	suffix := "-aws-ocp-411"
+
+	tests := []struct {
+		name             string
+		r                Repository
+		test             *Test
+		openShiftVersion string
+		want             string
+	}{
+		{
+			name: fmt.Sprintf("%d length name", maxNameLength),
+			r:    Repository{},
+			test: &Test{
+				Command: strings.Repeat("a", maxNameLength),
+			},
+			openShiftVersion: openshiftVersion,
+			want:             fmt.Sprintf("%s-%s%s", strings.Repeat("a", maxNameLength-len(suffix)-len(continuousSuffix)-shaLength-1) /* hex sha1 */, "32e067e", suffix),
+		},
+		{
+			name: fmt.Sprintf("%d length name", maxNameLength-len(suffix)-len(continuousSuffix)+1),
+			r:    Repository{},
+			test: &Test{
+				Command: strings.Repeat("a", maxNameLength-len(suffix)-len(continuousSuffix)+1),
+			},
+			openShiftVersion: openshiftVersion,
+			want:             fmt.Sprintf("%s-%s%s", strings.Repeat("a", maxNameLength-len(suffix)-len(continuousSuffix)-shaLength-1) /* hex sha1 */, "38666b8", suffix),
+		},
+		{
+			name: fmt.Sprintf("%d length name", maxNameLength-len(suffix)-len(continuousSuffix)),
+			r:    Repository{},
+			test: &Test{
+				Command: strings.Repeat("a", maxNameLength-len(suffix)-len(continuousSuffix)),
+			},
+			openShiftVersion: openshiftVersion,
+			want:             fmt.Sprintf("%s%s", strings.Repeat("a", maxNameLength-len(suffix)-len(continuousSuffix)), suffix),
+		},
+		{
+			name: "test-conformance name",
+			r:    Repository{},
+			test: &Test{
+				Command: "test-conformance",
+			},
+			openShiftVersion: openshiftVersion,
+			want:             fmt.Sprintf("%s%s", "test-conformance", suffix),
+		},
+		{
+			name: "test-kafka-broker-upstream-nightly",
+			r:    Repository{},
+			test: &Test{
+				Command: "test-kafka-broker-upstream-nightly",
+			},
+			openShiftVersion: openshiftVersion,
+			want:             fmt.Sprintf("%s%s", "test-kafka-fbbddbf", suffix),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.want) > maxNameLength-len(continuousSuffix) {
+				t.Fatalf("Test misconfiguration want cannot be longer than %d, got %d", maxNameLength-len(continuousSuffix), len(tt.want))
+			}
+			got := ToName(tt.r, tt.test, tt.openShiftVersion)
+			if got != tt.want {
+				t.Errorf("ToName() = %v (length %d), want %v (length %d)", got, len(got), tt.want, len(tt.want))
+			}
+			t.Logf("ToName() = %v (length %d), want %v (length %d)", got, len(got), tt.want, len(tt.want))
+		})
+	}
+}
+
+func TestNamingPolicyHash(t *testing.T) {
+	// echo -n "test-e2e" | sha1sum
+	want := "6a0d528"
+	if got := DefaultNamingPolicy.Hash("test-e2e"); got != want {
+		t.Errorf("Hash() = %v, want %v", got, want)
+	}
+}
+
+func TestNamingPolicyImageName(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		context imageContext
+		image   string
+		want    string
+	}{
+		{
+			name:   "production image",
+			prefix: "knative-eventing",
+			image:  "dispatcher",
+			want:   "knative-eventing-dispatcher",
+		},
+		{
+			name:    "test image",
+			prefix:  "knative-eventing",
+			context: TestContext,
+			image:   "event_sender",
+			want:    "knative-eventing-test-event-sender",
+		},
+		{
+			name:    "long image",
+			prefix:  "knative-eventing-kafka-broker",
+			context: TestContext,
+			image:   "kafka-consumer-group-lag-provider-test",
+			// Image names are never truncated.
+			want: "knative-eventing-kafka-broker-test-kafka-consumer-group-lag-provider-test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultNamingPolicy.ImageName(tt.prefix, tt.context, tt.image)
+			if got != tt.want {
+				t.Errorf("ImageName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamingPolicyValidate(t *testing.T) {
+	metadata := cioperatorapi.Metadata{Org: "openshift-knative", Repo: "eventing", Branch: "release-next", Variant: "412"}
+
+	tests := []struct {
+		name    string
+		cfgs    []ReleaseBuildConfiguration
+		wantErr bool
+	}{
+		{
+			name: "no collisions",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests: []cioperatorapi.TestStepConfiguration{
+							{As: "test-e2e-aws-ocp-412"},
+							{As: "test-e2e-aws-ocp-412-continuous", Cron: pointer.String("0 5 * * 2,6")},
+						},
+						Images: []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+							{To: "knative-eventing-dispatcher"},
+							{To: "knative-eventing-test-dispatcher"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test collision",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests: []cioperatorapi.TestStepConfiguration{
+							{As: "test-e2e-aws-ocp-412"},
+							{As: "test-e2e-aws-ocp-412"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "job collision across configurations",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests:    []cioperatorapi.TestStepConfiguration{{As: "test-e2e-aws-ocp-412"}},
+					},
+				},
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests:    []cioperatorapi.TestStepConfiguration{{As: "test-e2e-aws-ocp-412"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "image collision",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Images: []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+							{To: "knative-eventing-dispatcher"},
+							{To: "knative-eventing-dispatcher"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test name too long",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests: []cioperatorapi.TestStepConfiguration{
+							{
+								As:           strings.Repeat("a", maxNameLength+1),
+								ClusterClaim: &cioperatorapi.ClusterClaim{},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "job name too long",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						// pull-ci-openshift-knative-eventing-release-next-412- is 52 characters long.
+						Tests: []cioperatorapi.TestStepConfiguration{{As: strings.Repeat("a", maxJobNameLength-51)}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "job name at the limit",
+			cfgs: []ReleaseBuildConfiguration{
+				{
+					ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+						Metadata: metadata,
+						Tests:    []cioperatorapi.TestStepConfiguration{{As: strings.Repeat("a", maxJobNameLength-52)}},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultNamingPolicy.Validate(tt.cfgs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNamingPolicyMainPackageImageName(t *testing.T) {
+	tests := []struct {
+		name        string
+		scheme      ImageNamingScheme
+		mainPackage string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "default",
+			mainPackage: "cmd/kafka/controller",
+			want:        "controller",
+		},
+		{
+			name:        "base",
+			scheme:      BaseImageNaming,
+			mainPackage: "cmd/kafka/controller",
+			want:        "controller",
+		},
+		{
+			name:        "path",
+			scheme:      PathImageNaming,
+			mainPackage: "cmd/kafka/controller",
+			want:        "kafka-controller",
+		},
+		{
+			name:        "path test image",
+			scheme:      PathImageNaming,
+			mainPackage: "test/test_images/event_sender",
+			want:        "event_sender",
+		},
+		{
+			name:        "path vendored",
+			scheme:      PathImageNaming,
+			mainPackage: "vendor/knative.dev/reconciler-test/cmd/eventshub",
+			want:        "eventshub",
+		},
+		{
+			name:        "path without cmd",
+			scheme:      PathImageNaming,
+			mainPackage: "vendor/knative.dev/pkg/tools/migrate",
+			want:        "knative.dev-pkg-tools-migrate",
+		},
+		{
+			name:        "unknown",
+			scheme:      "hash",
+			mainPackage: "cmd/controller",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DefaultNamingPolicy.MainPackageImageName(tt.scheme, tt.mainPackage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MainPackageImageName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MainPackageImageName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageNameCollisions(t *testing.T) {
+	err := ImageNameCollisions(map[string][]string{
+		"knative-eventing-controller": {
+			"openshift/ci-operator/knative-images/controller/Dockerfile",
+			"openshift/ci-operator/other-images/controller/Dockerfile",
+		},
+		"knative-eventing-webhook": {"openshift/ci-operator/knative-images/webhook/Dockerfile"},
+	})
+	want := `image name collisions:
+image name knative-eventing-controller collides for openshift/ci-operator/knative-images/controller/Dockerfile, openshift/ci-operator/other-images/controller/Dockerfile`
+	if err == nil || err.Error() != want {
+		t.Errorf("ImageNameCollisions() = %v, want %v", err, want)
+	}
+
+	if err := ImageNameCollisions(map[string][]string{"knative-eventing-webhook": {"webhook/Dockerfile"}}); err != nil {
+		t.Errorf("ImageNameCollisions() = %v, want nil", err)
+	}
+}
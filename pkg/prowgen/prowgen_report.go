@@ -0,0 +1,159 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Report is a machine-readable summary of a prowgen run, it is meant to be consumed by
+// dashboards, release checklists and to build the openshift/release PR body.
+type Report struct {
+	// Config is the prowgen config file the report has been generated from.
+	Config  string        `json:"config"`
+	Entries []ReportEntry `json:"entries"`
+	// DeletedFiles are previously generated files that have been deleted and not generated again,
+	// for example, configurations of branches removed from the config.
+	DeletedFiles []string `json:"deletedFiles"`
+}
+
+// ReportEntry describes the configuration generated for a repository, branch and variant.
+type ReportEntry struct {
+	Org       string `json:"org"`
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch"`
+	Variant   string `json:"variant"`
+	SourceSHA string `json:"sourceSHA"`
+
+	// ConfigFile is the generated ci-operator configuration file.
+	ConfigFile string `json:"configFile"`
+	// BaseImages maps base image names to <namespace>/<name>:<tag>.
+	BaseImages map[string]string `json:"baseImages,omitempty"`
+	Images     []ReportImage     `json:"images"`
+	Tests      []ReportTest      `json:"tests"`
+	Promotion  *ReportPromotion  `json:"promotion,omitempty"`
+	// MirroringFiles are the generated image mirroring configuration files.
+	MirroringFiles []string `json:"mirroringFiles,omitempty"`
+}
+
+// ReportImage describes an image build discovered from a Dockerfile.
+type ReportImage struct {
+	Name           string `json:"name"`
+	DockerfilePath string `json:"dockerfilePath"`
+	// Inputs are the base images replaced in the Dockerfile.
+	Inputs []string `json:"inputs,omitempty"`
+}
+
+// ReportTest describes a generated test.
+type ReportTest struct {
+	// Target is the Makefile target the test runs, it is empty for tests defined in the config.
+	Target   string `json:"target,omitempty"`
+	As       string `json:"as"`
+	Periodic bool   `json:"periodic,omitempty"`
+}
+
+// ReportPromotion describes where images are promoted.
+type ReportPromotion struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+}
+
+// NewReportEntries creates report entries for the given configurations and the image mirroring
+// configurations generated from them.
+func NewReportEntries(outConfig string, cfgs []ReleaseBuildConfiguration, mirroring []ImageMirroringConfig) []ReportEntry {
+	entries := make([]ReportEntry, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		entry := ReportEntry{
+			Org:        cfg.Metadata.Org,
+			Repo:       cfg.Metadata.Repo,
+			Branch:     cfg.Metadata.Branch,
+			Variant:    cfg.Metadata.Variant,
+			SourceSHA:  cfg.SourceSHA,
+			ConfigFile: filepath.Join(outConfig, cfg.Path),
+			Images:     make([]ReportImage, 0, len(cfg.Images)),
+			Tests:      make([]ReportTest, 0, len(cfg.Tests)),
+		}
+
+		if len(cfg.BaseImages) > 0 {
+			entry.BaseImages = make(map[string]string, len(cfg.BaseImages))
+			for name, img := range cfg.BaseImages {
+				entry.BaseImages[name] = fmt.Sprintf("%s/%s:%s", img.Namespace, img.Name, img.Tag)
+			}
+		}
+
+		for _, img := range cfg.Images {
+			entry.Images = append(entry.Images, ReportImage{
+				Name:           string(img.To),
+				DockerfilePath: img.DockerfilePath,
+				Inputs:         sets.StringKeySet(img.Inputs).List(),
+			})
+		}
+
+		for _, test := range cfg.Tests {
+			entry.Tests = append(entry.Tests, ReportTest{
+				Target:   makeTarget(test),
+				As:       test.As,
+				Periodic: test.Cron != nil || test.Interval != nil,
+			})
+		}
+
+		if p := cfg.PromotionConfiguration; p != nil {
+			entry.Promotion = &ReportPromotion{Namespace: p.Namespace, Name: p.Name, Tag: p.Tag}
+		}
+
+		for _, m := range mirroring {
+			if m.Metadata == cfg.Metadata {
+				entry.MirroringFiles = append(entry.MirroringFiles, m.Path)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// makeTarget returns the Makefile target run by the given test, if any.
+func makeTarget(test cioperatorapi.TestStepConfiguration) string {
+	if test.MultiStageTestConfiguration == nil {
+		return ""
+	}
+	for _, step := range test.MultiStageTestConfiguration.Test {
+		if step.LiteralTestStep == nil || step.LiteralTestStep.As != "test" {
+			continue
+		}
+		if target := strings.TrimPrefix(step.LiteralTestStep.Commands, "make "); target != step.LiteralTestStep.Commands {
+			return target
+		}
+	}
+	return ""
+}
+
+// Write writes the report as JSON to the given path, "-" writes it to stdout.
+func (r *Report) Write(path string) error {
+	sort.Slice(r.Entries, func(i, j int) bool {
+		return r.Entries[i].ConfigFile < r.Entries[j].ConfigFile
+	})
+	r.DeletedFiles = sets.NewString(r.DeletedFiles...).List()
+
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	out = append(out, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(path, out, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package prowgen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestReport(t *testing.T) {
+	metadata := cioperatorapi.Metadata{Org: "openshift-knative", Repo: "eventing", Branch: "release-next", Variant: "411"}
+	cfgs := []ReleaseBuildConfiguration{
+		{
+			ReleaseBuildConfiguration: cioperatorapi.ReleaseBuildConfiguration{
+				Metadata: metadata,
+				InputConfiguration: cioperatorapi.InputConfiguration{
+					BaseImages: map[string]cioperatorapi.ImageStreamTagReference{
+						"openshift_knative-v1.8.0_knative-eventing-src": {Namespace: "openshift", Name: "knative-v1.8.0", Tag: "knative-eventing-src"},
+					},
+				},
+				PromotionConfiguration: &cioperatorapi.PromotionConfiguration{Namespace: "openshift", Name: "knative-nightly"},
+				Images: []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+					{
+						To: "knative-eventing-dispatcher",
+						ProjectDirectoryImageBuildInputs: cioperatorapi.ProjectDirectoryImageBuildInputs{
+							DockerfilePath: "openshift/ci-operator/knative-images/dispatcher/Dockerfile",
+							Inputs: map[string]cioperatorapi.ImageBuildInputs{
+								"openshift_knative-v1.8.0_knative-eventing-src": {As: []string{"src"}},
+							},
+						},
+					},
+				},
+				Tests: []cioperatorapi.TestStepConfiguration{
+					{
+						As: "test-e2e-aws-ocp-411",
+						MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+							Test: []cioperatorapi.TestStep{{LiteralTestStep: &cioperatorapi.LiteralTestStep{As: "test", Commands: "make test-e2e"}}},
+						},
+					},
+					{
+						As:   "test-e2e-aws-ocp-411-continuous",
+						Cron: pointer.String("0 5 * * 2,6"),
+						MultiStageTestConfiguration: &cioperatorapi.MultiStageTestConfiguration{
+							Test: []cioperatorapi.TestStep{{LiteralTestStep: &cioperatorapi.LiteralTestStep{As: "test", Commands: "make test-e2e"}}},
+						},
+					},
+					{
+						As: "unit",
+					},
+				},
+			},
+			Path:      "openshift-knative/eventing/openshift-knative-eventing-release-next__411.yaml",
+			Branch:    "release-next",
+			SourceSHA: "abcdef",
+		},
+	}
+	mirroring := []ImageMirroringConfig{
+		{Path: "openshift/release/mapping_knative_knative-nightly_eventing_quay", Metadata: metadata},
+		{Path: "openshift/release/mapping_knative_knative-nightly_serving_quay", Metadata: cioperatorapi.Metadata{Org: "openshift-knative", Repo: "serving"}},
+	}
+
+	report := &Report{
+		Config:       "config/eventing.yaml",
+		Entries:      NewReportEntries("ci-operator/config", cfgs, mirroring),
+		DeletedFiles: []string{"b", "a", "b"},
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Report{}
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Report{
+		Config: "config/eventing.yaml",
+		Entries: []ReportEntry{
+			{
+				Org:        "openshift-knative",
+				Repo:       "eventing",
+				Branch:     "release-next",
+				Variant:    "411",
+				SourceSHA:  "abcdef",
+				ConfigFile: "ci-operator/config/openshift-knative/eventing/openshift-knative-eventing-release-next__411.yaml",
+				BaseImages: map[string]string{
+					"openshift_knative-v1.8.0_knative-eventing-src": "openshift/knative-v1.8.0:knative-eventing-src",
+				},
+				Images: []ReportImage{
+					{
+						Name:           "knative-eventing-dispatcher",
+						DockerfilePath: "openshift/ci-operator/knative-images/dispatcher/Dockerfile",
+						Inputs:         []string{"openshift_knative-v1.8.0_knative-eventing-src"},
+					},
+				},
+				Tests: []ReportTest{
+					{Target: "test-e2e", As: "test-e2e-aws-ocp-411"},
+					{Target: "test-e2e", As: "test-e2e-aws-ocp-411-continuous", Periodic: true},
+					{As: "unit"},
+				},
+				Promotion:      &ReportPromotion{Namespace: "openshift", Name: "knative-nightly"},
+				MirroringFiles: []string{"openshift/release/mapping_knative_knative-nightly_eventing_quay"},
+			},
+		},
+		DeletedFiles: []string{"a", "b"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal("(-want, +got)", diff)
+	}
+}
@@ -0,0 +1,125 @@
+package prowgen
+
+import (
+	"fmt"
+	"regexp"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	SmallResourceProfile  = "small"
+	MediumResourceProfile = "medium"
+	LargeResourceProfile  = "large"
+)
+
+// ResourceProfiles maps a profile name to the resource requirements it represents.
+type ResourceProfiles map[string]cioperatorapi.ResourceRequirements
+
+// ResourceProfileMatch assigns a resource profile to every image or test matching
+// at least one of the given regular expressions.
+type ResourceProfileMatch struct {
+	Matches []string `json:"matches" yaml:"matches"`
+	Profile string   `json:"profile" yaml:"profile"`
+}
+
+// RepositoryResourceProfiles holds the resource profile assignments for a repository.
+//
+// Images are matched by image name (for example, knative-eventing-kafka-broker-dispatcher),
+// tests are matched by Makefile target (for example, test-e2e).
+type RepositoryResourceProfiles struct {
+	Images []ResourceProfileMatch `json:"images" yaml:"images"`
+	Tests  []ResourceProfileMatch `json:"tests" yaml:"tests"`
+}
+
+// defaultResourceProfiles are the profiles available without any configuration, they can be
+// overridden in the config file using `config.resourceProfiles`.
+var defaultResourceProfiles = ResourceProfiles{
+	SmallResourceProfile: {
+		Requests: cioperatorapi.ResourceList{
+			"cpu":    "100m",
+			"memory": "256Mi",
+		},
+		Limits: cioperatorapi.ResourceList{
+			"memory": "1Gi",
+		},
+	},
+	MediumResourceProfile: {
+		Requests: cioperatorapi.ResourceList{
+			"cpu":    "500m",
+			"memory": "1Gi",
+		},
+		Limits: cioperatorapi.ResourceList{
+			"memory": "4Gi",
+		},
+	},
+	LargeResourceProfile: {
+		Requests: cioperatorapi.ResourceList{
+			"cpu":    "1",
+			"memory": "4Gi",
+		},
+		Limits: cioperatorapi.ResourceList{
+			"memory": "8Gi",
+		},
+	},
+}
+
+// WithDefaults returns the default profiles overridden by the given profiles.
+func (p ResourceProfiles) WithDefaults() ResourceProfiles {
+	profiles := make(ResourceProfiles, len(defaultResourceProfiles)+len(p))
+	for name, req := range defaultResourceProfiles {
+		profiles[name] = req
+	}
+	for name, req := range p {
+		profiles[name] = req
+	}
+	return profiles
+}
+
+// Resources returns the resource requirements of the first profile assigned by the given matches
+// to name, it returns false when no match applies.
+func (p ResourceProfiles) Resources(matches []ResourceProfileMatch, name string) (cioperatorapi.ResourceRequirements, bool, error) {
+	for _, m := range matches {
+		for _, pattern := range m.Matches {
+			matched, err := regexp.MatchString(pattern, name)
+			if err != nil {
+				return cioperatorapi.ResourceRequirements{}, false, fmt.Errorf("failed to match %s to resource profile %q pattern %s: %w", name, m.Profile, pattern, err)
+			}
+			if !matched {
+				continue
+			}
+			req, ok := p[m.Profile]
+			if !ok {
+				return cioperatorapi.ResourceRequirements{}, false, fmt.Errorf("unknown resource profile %q for %s", m.Profile, name)
+			}
+			return *req.DeepCopy(), true, nil
+		}
+	}
+	return cioperatorapi.ResourceRequirements{}, false, nil
+}
+
+// WithImagesResources sets resources for every image build matching a resource profile.
+// It must be applied after images are discovered.
+//
+// Explicit per-image resources in Repository.Resources take precedence over profiles.
+func WithImagesResources(r Repository, profiles ResourceProfiles) ReleaseBuildConfigurationOption {
+	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
+		for _, img := range cfg.Images {
+			if _, ok := r.Resources[string(img.To)]; ok {
+				continue
+			}
+			req, ok, err := profiles.Resources(r.ResourceProfiles.Images, string(img.To))
+			if err != nil {
+				return fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+			}
+			if !ok {
+				continue
+			}
+			if cfg.Resources == nil {
+				cfg.Resources = make(cioperatorapi.ResourceConfiguration)
+			}
+			cfg.Resources[string(img.To)] = req
+		}
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+package prowgen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestResourceProfiles(t *testing.T) {
+
+	r := Repository{
+		Org:                   "testdata",
+		Repo:                  "eventing",
+		ImagePrefix:           "knative-eventing",
+		CanonicalGoRepository: pointer.String("knative.dev/eventing"),
+		E2ETests: E2ETests{
+			Matches: []string{
+				"test-e2e$",
+				"test-reconcile.*",
+			},
+		},
+		ResourceProfiles: RepositoryResourceProfiles{
+			Images: []ResourceProfileMatch{
+				{
+					Matches: []string{".*-dispatcher$"},
+					Profile: LargeResourceProfile,
+				},
+			},
+			Tests: []ResourceProfileMatch{
+				{
+					Matches: []string{"^test-e2e$"},
+					Profile: "custom",
+				},
+			},
+		},
+	}
+
+	custom := cioperatorapi.ResourceRequirements{
+		Requests: cioperatorapi.ResourceList{
+			"cpu":    "2",
+			"memory": "6Gi",
+		},
+		Limits: cioperatorapi.ResourceList{
+			"memory": "12Gi",
+		},
+	}
+
+	profiles := ResourceProfiles{"custom": custom}.WithDefaults()
+
+	cfg := cioperatorapi.ReleaseBuildConfiguration{}
+	options := []ReleaseBuildConfigurationOption{
+		DiscoverImages(r),
+		WithImagesResources(r, profiles),
+		DiscoverTests(r, "4.12", profiles),
+	}
+	if err := applyOptions(&cfg, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedResources := cioperatorapi.ResourceConfiguration{
+		"knative-eventing-dispatcher": defaultResourceProfiles[LargeResourceProfile],
+	}
+	if diff := cmp.Diff(expectedResources, cfg.Resources); diff != "" {
+		t.Errorf("Unexpected resources (-want, +got): \n%s", diff)
+	}
+
+	expectedTestResources := map[string]cioperatorapi.ResourceRequirements{
+		"test-e2e-aws-ocp-412":                   custom,
+		"test-e2e-aws-ocp-412-continuous":        custom,
+		"test-reconciler-aws-ocp-412":            {Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+		"test-reconciler-aws-ocp-412-continuous": {Requests: cioperatorapi.ResourceList{"cpu": "100m"}},
+	}
+	if len(cfg.Tests) != len(expectedTestResources) {
+		t.Fatalf("expected %d tests, got %d", len(expectedTestResources), len(cfg.Tests))
+	}
+	for _, test := range cfg.Tests {
+		expected, ok := expectedTestResources[test.As]
+		if !ok {
+			t.Fatalf("Unexpected test %s", test.As)
+		}
+		got := test.MultiStageTestConfiguration.Test[0].LiteralTestStep.Resources
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("Unexpected resources for test %s (-want, +got): \n%s", test.As, diff)
+		}
+	}
+}
+
+func TestResourceProfilesUnknownProfile(t *testing.T) {
+	matches := []ResourceProfileMatch{
+		{
+			Matches: []string{".*"},
+			Profile: "unknown",
+		},
+	}
+
+	profiles := ResourceProfiles{}.WithDefaults()
+	if _, _, err := profiles.Resources(matches, "test-e2e"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
+
+func TestResourceProfilesExplicitResourcesWin(t *testing.T) {
+	explicit := cioperatorapi.ResourceRequirements{
+		Requests: cioperatorapi.ResourceList{"cpu": "3"},
+	}
+	r := Repository{
+		ImagePrefix: "knative-eventing",
+		Resources: cioperatorapi.ResourceConfiguration{
+			"knative-eventing-dispatcher": explicit,
+		},
+		ResourceProfiles: RepositoryResourceProfiles{
+			Images: []ResourceProfileMatch{
+				{
+					Matches: []string{".*"},
+					Profile: LargeResourceProfile,
+				},
+			},
+		},
+	}
+
+	cfg := cioperatorapi.ReleaseBuildConfiguration{
+		Images: []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration{
+			{To: "knative-eventing-dispatcher"},
+			{To: "knative-eventing-webhook"},
+		},
+		Resources: cioperatorapi.ResourceConfiguration{
+			"knative-eventing-dispatcher": explicit,
+		},
+	}
+	if err := applyOptions(&cfg, WithImagesResources(r, ResourceProfiles{}.WithDefaults())); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedResources := cioperatorapi.ResourceConfiguration{
+		"knative-eventing-dispatcher": explicit,
+		"knative-eventing-webhook":    defaultResourceProfiles[LargeResourceProfile],
+	}
+	if diff := cmp.Diff(expectedResources, cfg.Resources); diff != "" {
+		t.Errorf("Unexpected resources (-want, +got): \n%s", diff)
+	}
+}
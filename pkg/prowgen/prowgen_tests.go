@@ -1,12 +1,9 @@
 package prowgen
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -18,16 +15,39 @@ import (
 	"k8s.io/utils/pointer"
 )
 
-func DiscoverTests(r Repository, openShiftVersion string) ReleaseBuildConfigurationOption {
+func DiscoverTests(r Repository, openShiftVersion string, profiles ResourceProfiles) ReleaseBuildConfigurationOption {
 	return func(cfg *cioperatorapi.ReleaseBuildConfiguration) error {
 		tests, err := discoverE2ETests(r)
 		if err != nil {
 			return err
 		}
 
+		ir, err := newImageDependencyResolver(r, cfg.Images)
+		if err != nil {
+			return err
+		}
+
 		for i := range tests {
 			test := &tests[i]
 			as := ToName(r, test, openShiftVersion)
+
+			resources, ok, err := profiles.Resources(r.ResourceProfiles.Tests, test.Command)
+			if err != nil {
+				return fmt.Errorf("[%s] %w", r.RepositoryDirectory(), err)
+			}
+			if !ok {
+				resources = cioperatorapi.ResourceRequirements{
+					Requests: cioperatorapi.ResourceList{
+						"cpu": "100m",
+					},
+				}
+			}
+
+			dependencies, err := ir.dependencies(test)
+			if err != nil {
+				return err
+			}
+
 			testConfiguration := cioperatorapi.TestStepConfiguration{
 				As: as,
 				ClusterClaim: &cioperatorapi.ClusterClaim{
@@ -43,16 +63,12 @@ func DiscoverTests(r Repository, openShiftVersion string) ReleaseBuildConfigurat
 					Test: []cioperatorapi.TestStep{
 						{
 							LiteralTestStep: &cioperatorapi.LiteralTestStep{
-								As:       "test",
-								From:     "src",
-								Commands: fmt.Sprintf("make %s", test.Command),
-								Resources: cioperatorapi.ResourceRequirements{
-									Requests: cioperatorapi.ResourceList{
-										"cpu": "100m",
-									},
-								},
+								As:           "test",
+								From:         "src",
+								Commands:     fmt.Sprintf("make %s", test.Command),
+								Resources:    resources,
 								Timeout:      &prowapi.Duration{Duration: 4 * time.Hour},
-								Dependencies: dependenciesFromImages(cfg.Images),
+								Dependencies: dependencies,
 								Cli:          "latest",
 							},
 						},
@@ -95,7 +111,7 @@ func DiscoverTests(r Repository, openShiftVersion string) ReleaseBuildConfigurat
 			cfg.Tests = append(cfg.Tests, testConfiguration)
 
 			cronTestConfiguration := testConfiguration.DeepCopy()
-			cronTestConfiguration.As += "-continuous"
+			cronTestConfiguration.As += continuousSuffix
 			cronTestConfiguration.Cron = pointer.String("0 5 * * 2,6")
 
 			cfg.Tests = append(cfg.Tests, *cronTestConfiguration)
@@ -105,20 +121,14 @@ func DiscoverTests(r Repository, openShiftVersion string) ReleaseBuildConfigurat
 	}
 }
 
-const (
-	shaLength = 7
-)
-
 type Test struct {
 	Command string
-}
-
-func (t *Test) HexSha() string {
-	return hex.EncodeToString(sha1.New().Sum([]byte(t.Command)))[:shaLength]
+	// Recipe is the list of commands of the Makefile target.
+	Recipe []string
 }
 
 func discoverE2ETests(r Repository) ([]Test, error) {
-	mc, err := os.ReadFile(filepath.Join(r.RepositoryDirectory(), "Makefile"))
+	mc, err := readRepositoryFile(context.Background(), r, "Makefile")
 	if err != nil {
 		return nil, fmt.Errorf("[%s] failed to read file %s: %w", r.RepositoryDirectory(), "Makefile", err)
 	}
@@ -136,6 +146,11 @@ func discoverE2ETests(r Repository) ([]Test, error) {
 		}
 	}
 
+	recipes := makeTargetsRecipes(lines)
+	for i := range targets {
+		targets[i].Recipe = recipes[targets[i].Command]
+	}
+
 	sort.Slice(targets, func(i, j int) bool {
 		return targets[i].Command < targets[j].Command
 	})
@@ -162,6 +177,29 @@ func createTest(r Repository, line string, shouldMatch string, tests *[]Test, co
 	return nil
 }
 
+// makeTargetsRecipes returns the recipe lines for each target defined in the given Makefile lines.
+func makeTargetsRecipes(lines []string) map[string][]string {
+	recipes := make(map[string][]string)
+	target := ""
+	for _, l := range lines {
+		if strings.HasPrefix(l, "\t") {
+			if target != "" {
+				recipes[target] = append(recipes[target], strings.TrimSpace(l))
+			}
+			continue
+		}
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		target = ""
+		if strings.HasSuffix(l, ":") {
+			target = strings.TrimSuffix(l, ":")
+		}
+	}
+	return recipes
+}
+
 func dependenciesFromImages(images []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration) []cioperatorapi.StepDependency {
 	deps := make([]cioperatorapi.StepDependency, 0, len(images))
 	for _, image := range images {
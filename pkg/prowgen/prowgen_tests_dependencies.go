@@ -0,0 +1,176 @@
+package prowgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	imageEnvRegex = regexp.MustCompile(`KNATIVE_[A-Z0-9_]+`)
+	scriptRegex   = regexp.MustCompile(`[\w./-]+\.(sh|bash)\b`)
+)
+
+// imageDependencyResolver resolves the images a test depends on.
+type imageDependencyResolver struct {
+	r      Repository
+	images []cioperatorapi.StepDependency
+	// scripts caches the env variables referenced by a script, including the scripts it references.
+	scripts map[string]sets.String
+}
+
+func newImageDependencyResolver(r Repository, images []cioperatorapi.ProjectDirectoryImageBuildStepConfiguration) (*imageDependencyResolver, error) {
+	for _, d := range r.E2ETests.Dependencies {
+		for _, m := range append(append([]string{}, d.Matches...), d.Images...) {
+			if _, err := regexp.Compile(m); err != nil {
+				return nil, fmt.Errorf("[%s] invalid test dependencies regular expression %s: %w", r.RepositoryDirectory(), m, err)
+			}
+		}
+	}
+	return &imageDependencyResolver{
+		r:       r,
+		images:  dependenciesFromImages(images),
+		scripts: make(map[string]sets.String),
+	}, nil
+}
+
+// dependencies returns the images the given test depends on.
+//
+// Declared dependencies take precedence over inferred dependencies, when neither of them
+// applies, the test depends on every image.
+func (ir *imageDependencyResolver) dependencies(test *Test) ([]cioperatorapi.StepDependency, error) {
+	for _, d := range ir.r.E2ETests.Dependencies {
+		if !matchAny(d.Matches, test.Command) {
+			continue
+		}
+		deps := make([]cioperatorapi.StepDependency, 0, len(ir.images))
+		for _, img := range ir.images {
+			if matchAny(d.Images, img.Name) {
+				deps = append(deps, img)
+			}
+		}
+		// A pattern matching no image is likely a typo, silently dropping the dependency.
+		for _, pattern := range d.Images {
+			if !ir.matchesAnyImage(pattern) {
+				return nil, fmt.Errorf("[%s] test %s: dependencies image pattern %q matches no image", ir.r.RepositoryDirectory(), test.Command, pattern)
+			}
+		}
+		return deps, nil
+	}
+
+	if !ir.r.E2ETests.InferDependencies {
+		return ir.images, nil
+	}
+
+	envs, err := ir.inferEnvs(test)
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]cioperatorapi.StepDependency, 0, len(ir.images))
+	for _, img := range ir.images {
+		if envs.Has(img.Env) {
+			deps = append(deps, img)
+		}
+	}
+	if len(deps) == 0 {
+		log.Println(ir.r.RepositoryDirectory(), "No dependencies inferred for test", test.Command, "depending on every image")
+		return ir.images, nil
+	}
+	log.Println(ir.r.RepositoryDirectory(), "Inferred dependencies for test", test.Command, deps)
+	return deps, nil
+}
+
+func (ir *imageDependencyResolver) matchesAnyImage(pattern string) bool {
+	for _, img := range ir.images {
+		if matchAny([]string{pattern}, img.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inferEnvs returns the image env variables referenced by the test recipe and by the scripts
+// it runs.
+func (ir *imageDependencyResolver) inferEnvs(test *Test) (sets.String, error) {
+	envs := sets.NewString()
+	for _, l := range test.Recipe {
+		envs.Insert(imageEnvRegex.FindAllString(l, -1)...)
+		for _, script := range scriptRegex.FindAllString(l, -1) {
+			scriptEnvs, err := ir.scriptEnvs(".", script)
+			if err != nil {
+				return nil, err
+			}
+			envs = envs.Union(scriptEnvs)
+		}
+	}
+	return envs, nil
+}
+
+// scriptEnvs returns the image env variables referenced by the given script and by the scripts
+// it references.
+//
+// The script path is resolved relative to the repository and relative to the given directory,
+// relative to the repository, references that don't resolve to a file are ignored.
+// Scripts are read at the branch ref, like every other discovered file.
+func (ir *imageDependencyResolver) scriptEnvs(dir string, script string) (sets.String, error) {
+	ctx := context.Background()
+
+	path := ""
+	var content []byte
+	for _, c := range []string{filepath.Clean(script), filepath.Join(dir, script)} {
+		if c == ".." || strings.HasPrefix(c, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if _, ok := ir.scripts[c]; ok {
+			path = c
+			break
+		}
+		b, err := readRepositoryFile(ctx, ir.r, c)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[%s] failed to read file %s: %w", ir.r.RepositoryDirectory(), c, err)
+		}
+		path, content = c, b
+		break
+	}
+	if path == "" {
+		return sets.NewString(), nil
+	}
+
+	if envs, ok := ir.scripts[path]; ok {
+		return envs, nil
+	}
+	// Guard against scripts referencing each other.
+	ir.scripts[path] = sets.NewString()
+
+	envs := sets.NewString(imageEnvRegex.FindAllString(string(content), -1)...)
+	for _, s := range scriptRegex.FindAllString(string(content), -1) {
+		scriptEnvs, err := ir.scriptEnvs(filepath.Dir(path), strings.TrimPrefix(s, "/"))
+		if err != nil {
+			return nil, err
+		}
+		envs = envs.Union(scriptEnvs)
+	}
+	ir.scripts[path] = envs
+	return envs, nil
+}
+
+func matchAny(regexes []string, s string) bool {
+	for _, r := range regexes {
+		// regular expressions are validated in newImageDependencyResolver.
+		if matched, _ := regexp.MatchString(r, s); matched {
+			return true
+		}
+	}
+	return false
+}
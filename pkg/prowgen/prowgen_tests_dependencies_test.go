@@ -0,0 +1,193 @@
+package prowgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"k8s.io/utils/pointer"
+)
+
+func TestDiscoverTestsDependencies(t *testing.T) {
+
+	dispatcher := cioperatorapi.StepDependency{
+		Name: "knative-eventing-dispatcher",
+		Env:  "KNATIVE_EVENTING_DISPATCHER",
+	}
+	webhook := cioperatorapi.StepDependency{
+		Name: "knative-eventing-test-webhook",
+		Env:  "KNATIVE_EVENTING_TEST_WEBHOOK",
+	}
+
+	tests := []struct {
+		name     string
+		e2eTests E2ETests
+		want     map[string][]cioperatorapi.StepDependency
+		wantErr  string
+	}{
+		{
+			name: "all images by default",
+			e2eTests: E2ETests{
+				Matches: []string{"test-dispatcher-e2e", "test-e2e$"},
+			},
+			want: map[string][]cioperatorapi.StepDependency{
+				"test-dispatcher-e2e-aws-ocp-412": {dispatcher, webhook},
+				"test-e2e-aws-ocp-412":            {dispatcher, webhook},
+			},
+		},
+		{
+			name: "declared dependencies",
+			e2eTests: E2ETests{
+				Matches: []string{"test-dispatcher-e2e", "test-e2e$"},
+				Dependencies: []TestDependencies{
+					{
+						Matches: []string{"^test-e2e$"},
+						Images:  []string{".*-test-.*"},
+					},
+				},
+			},
+			want: map[string][]cioperatorapi.StepDependency{
+				"test-dispatcher-e2e-aws-ocp-412": {dispatcher, webhook},
+				"test-e2e-aws-ocp-412":            {webhook},
+			},
+		},
+		{
+			name: "inferred dependencies",
+			e2eTests: E2ETests{
+				Matches:           []string{"test-dispatcher-e2e", "test-e2e$"},
+				InferDependencies: true,
+			},
+			want: map[string][]cioperatorapi.StepDependency{
+				"test-dispatcher-e2e-aws-ocp-412": {dispatcher},
+				// Nothing is inferred, fallback to every image.
+				"test-e2e-aws-ocp-412": {dispatcher, webhook},
+			},
+		},
+		{
+			name: "declared dependencies take precedence over inferred dependencies",
+			e2eTests: E2ETests{
+				Matches:           []string{"test-dispatcher-e2e"},
+				InferDependencies: true,
+				Dependencies: []TestDependencies{
+					{
+						Matches: []string{".*"},
+						Images:  []string{".*-webhook$"},
+					},
+				},
+			},
+			want: map[string][]cioperatorapi.StepDependency{
+				"test-dispatcher-e2e-aws-ocp-412": {webhook},
+			},
+		},
+		{
+			name: "declared dependencies image pattern matching no image",
+			e2eTests: E2ETests{
+				Matches: []string{"test-e2e$"},
+				Dependencies: []TestDependencies{
+					{
+						Matches: []string{"^test-e2e$"},
+						Images:  []string{".*-webhook$", ".*-dispatcher-typo$"},
+					},
+				},
+			},
+			wantErr: `test test-e2e: dependencies image pattern ".*-dispatcher-typo$" matches no image`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Repository{
+				Org:                   "testdata",
+				Repo:                  "eventing",
+				ImagePrefix:           "knative-eventing",
+				CanonicalGoRepository: pointer.String("knative.dev/eventing"),
+				E2ETests:              tt.e2eTests,
+			}
+
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			options := []ReleaseBuildConfigurationOption{
+				DiscoverImages(r),
+				DiscoverTests(r, "4.12", ResourceProfiles{}.WithDefaults()),
+			}
+			err := applyOptions(&cfg, options...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string][]cioperatorapi.StepDependency)
+			for _, test := range cfg.Tests {
+				if test.Cron != nil {
+					continue
+				}
+				got[test.As] = test.MultiStageTestConfiguration.Test[0].LiteralTestStep.Dependencies
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected dependencies (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiscoverTestsDependenciesAtRef(t *testing.T) {
+	r := newTestGitRepository(t)
+	r.ImagePrefix = "knative-eventing"
+	r.E2ETests = E2ETests{Matches: []string{"test-e2e$"}, InferDependencies: true}
+
+	mustGit(t, r, "checkout", "-q", "-b", "main")
+	mustWriteFile(t, r, "Makefile", "test-e2e:\n\t./hack/e2e.sh\n")
+	mustWriteFile(t, r, "hack/e2e.sh", "echo $KNATIVE_EVENTING_DISPATCHER\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-images/dispatcher/Dockerfile", "FROM scratch\n")
+	mustWriteFile(t, r, "openshift/ci-operator/knative-images/webhook/Dockerfile", "FROM scratch\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "main")
+
+	mustGit(t, r, "checkout", "-q", "-b", "release-next")
+	mustWriteFile(t, r, "hack/e2e.sh", "source $(dirname $0)/lib.sh\n")
+	mustWriteFile(t, r, "hack/lib.sh", "echo $KNATIVE_EVENTING_WEBHOOK\n")
+	mustGit(t, r, "add", ".")
+	mustGit(t, r, "commit", "-q", "-m", "release-next")
+
+	// Scripts must be read at the ref, not from the checked out branch.
+	mustGit(t, r, "checkout", "-q", "main")
+	r.ref = "release-next"
+
+	previousReader := DiscoveryGitReader
+	t.Cleanup(func() { DiscoveryGitReader = previousReader })
+
+	for _, backend := range []string{ExecGitBackend, GoGitBackend} {
+		t.Run(backend, func(t *testing.T) {
+			reader, err := NewGitReader(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			DiscoveryGitReader = reader
+
+			cfg := cioperatorapi.ReleaseBuildConfiguration{}
+			options := []ReleaseBuildConfigurationOption{
+				DiscoverImages(r),
+				DiscoverTests(r, "4.12", ResourceProfiles{}.WithDefaults()),
+			}
+			if err := applyOptions(&cfg, options...); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(cfg.Tests) == 0 {
+				t.Fatal("expected test-e2e to be discovered")
+			}
+			want := []cioperatorapi.StepDependency{{Name: "knative-eventing-webhook", Env: "KNATIVE_EVENTING_WEBHOOK"}}
+			for _, test := range cfg.Tests {
+				got := test.MultiStageTestConfiguration.Test[0].LiteralTestStep.Dependencies
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("Unexpected dependencies for %s (-want, +got): \n%s", test.As, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+#!/usr/bin/env bash
+
+function run_dispatcher_e2e_tests() {
+  echo "Using ${KNATIVE_EVENTING_DISPATCHER}"
+}
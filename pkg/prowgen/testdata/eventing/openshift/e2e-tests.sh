@@ -0,0 +1,5 @@
+#!/usr/bin/env bash
+
+source "$(dirname "$0")/e2e-common.sh"
+
+run_dispatcher_e2e_tests
@@ -1,12 +1,13 @@
 package testselect
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"os"
-	"regexp"
 	"sort"
 	"strings"
 
@@ -22,129 +23,312 @@ const (
 // TestSuites holds mapping between file path regular expressions and
 // test suites that cover the paths.
 type TestSuites struct {
-	List []TestSuite `yaml:"testsuites"`
+	// Ignore lists gitignore-style patterns of paths that never trigger tests, for example,
+	// docs/ or OWNERS.
+	Ignore []string    `yaml:"ignore" jsonschema:"minLength=1"`
+	List   []TestSuite `yaml:"testsuites" jsonschema:"required"`
 }
 
 type TestSuite struct {
-	Name         string   `yaml:"name"`
-	RunIfChanged []string `yaml:"run_if_changed"`
+	Name string `yaml:"name" jsonschema:"required,minLength=1"`
+	// RunIfChanged lists regular expressions of paths triggering the suite.
+	RunIfChanged []string `yaml:"run_if_changed" jsonschema:"minLength=1,format=regex"`
+	// RunIfChangedPatterns lists gitignore-style patterns of paths triggering the suite, patterns
+	// starting with ! exclude paths matched by previous patterns.
+	RunIfChangedPatterns []string `yaml:"run_if_changed_patterns" jsonschema:"minLength=1"`
+	// SkipIfOnlyChanged lists gitignore-style patterns, the suite is skipped when every changed
+	// path matches them.
+	// A suite with only SkipIfOnlyChanged is triggered by every path not matching them.
+	SkipIfOnlyChanged []string `yaml:"skip_if_only_changed" jsonschema:"minLength=1"`
+	// Packages lists Go import paths, optionally ending with /..., the suite is triggered by
+	// changes to these packages and to packages they transitively depend on.
+	// It requires loading the Go package graph of the checkout.
+	Packages []string `yaml:"packages" jsonschema:"minLength=1"`
 	// Tests are arbitrary strings. It is up to the caller to check the strings and decide whether
 	// some code should be run. For example, they can match specific Bash function names or Make targets.
-	Tests []string `yaml:"tests"`
+	Tests []string `yaml:"tests" jsonschema:"minLength=1"`
+	// Cost is the expected cost of running the suite, for example, its runtime in minutes or its
+	// cluster usage, it is used to fit the selected suites in a budget.
+	// When it isn't set, it is derived from historical JUnit durations, if any.
+	Cost float64 `yaml:"cost" jsonschema:"minimum=0"`
+	// JUnit lists regular expressions of the JUnit test suite or test case names of the suite, the
+	// historical durations of the matching names are summed up to derive its cost, Tests are used
+	// as names when it isn't set.
+	// Expressions should match either test suites or their test cases, not both.
+	JUnit []string `yaml:"junit" jsonschema:"minLength=1,format=regex"`
+	// Risk weights the value of running the suite, suites with higher risk per unit of cost are
+	// kept first when the selected suites exceed the budget, it defaults to 1.
+	Risk float64 `yaml:"risk" jsonschema:"minimum=0"`
+}
+
+// ReadTestSuites reads the test suites file at the given path.
+func ReadTestSuites(path string) (*TestSuites, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	testSuites := new(TestSuites)
+	if err := yaml.UnmarshalStrict(in, testSuites); err != nil {
+		return nil, fmt.Errorf("unmarshal test suite mappings %s: %w", path, err)
+	}
+	if err := testSuites.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return testSuites, nil
 }
 
 func Main() {
 	ctx := context.Background()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(LintMain(ctx, os.Args[2:]))
+		case "schema":
+			os.Exit(SchemaMain())
+		}
+	}
+
 	ts := flag.String("testsuites", "testsuites.yaml", "Specify yaml file with path-to-testsuite mapping")
 	// Clonerefs options as defined in https://github.com/kubernetes/test-infra/blob/master/prow/clonerefs/options.go
 	refs := flag.String("clonerefs", "clonerefs.json", "Specify json file with clonerefs")
 	outFile := flag.String("output", "tests.txt", "Specify name of output file")
+	format := flag.String("format", TextOutputFormat, "Format of the output file: text, json, shell, make, junit or github")
+	expandAll := flag.Bool("expand-all", false, `Expand "All" to the tests of every suite`)
+	repoUnderTest := flag.String("repo", "", "Repository under test (<org>/<repo>) among clone refs and extra refs, defaults to the working directory refs or the first refs")
+	previousBaseSha := flag.String("previous-base-sha", "", "SHA the base SHA of postsubmits is compared to, defaults to the parent of the base SHA")
+	checkout := flag.String("checkout", "", "Existing checkout, such as the clonerefs checkout or a local clone, the diff is computed in it without cloning, fetching nor merging")
+	base := flag.String("base", "", "Base ref the head ref of the existing checkout is compared to from their merge-base, defaults to the base SHA of the clone refs")
+	head := flag.String("head", "HEAD", "Head ref of the existing checkout")
+	explain := flag.String("explain", "", "Write why each test has been selected to the given file, - writes it to stdout")
+	goPackages := flag.Bool("go-packages", false, "Load the Go package graph of the checkout to match suites declaring Go packages")
+	explainFormat := flag.String("explain-format", TextExplainFormat, "Format of the explanation: text or json")
+	budget := flag.Float64("budget", 0, "Maximum total cost of the selected suites, suites with the lowest risk per unit of cost are dropped first, 0 means no budget")
+	junitDir := flag.String("junit-dir", "", "Directory with JUnit results to derive the cost of suites without cost from historical durations")
+	forceAll := flag.Bool("force-all", false, "Select every test, also enabled by the "+ForceAllEnv+"=true environment variable or the "+ForceAllLabel+" label")
+	labels := flag.String("labels", "", "Comma separated list of the pull request labels")
 	flag.Parse()
 
 	log.Println(*ts, *refs, *outFile)
 
-	inRefs, err := os.ReadFile(*refs)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	cloneRefs := new(clonerefs.Options)
-	if err := json.Unmarshal(inRefs, cloneRefs); err != nil {
-		log.Fatalln("Unmarshal clone refs options", err)
+	// Clone refs are not needed when comparing two refs of an existing checkout.
+	if *checkout == "" || *base == "" {
+		inRefs, err := os.ReadFile(*refs)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := json.Unmarshal(inRefs, cloneRefs); err != nil {
+			log.Fatalln("Unmarshal clone refs options", err)
+		}
 	}
 
-	inTs, err := os.ReadFile(*ts)
+	testSuites, err := ReadTestSuites(*ts)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	testSuites := new(TestSuites)
-	if err := yaml.UnmarshalStrict(inTs, testSuites); err != nil {
-		log.Fatalln("Unmarshal test suite mappings", err)
+	gitRefs, err := SelectRefs(cloneRefs.GitRefs, *repoUnderTest)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	var tests, paths []string
-
-	if len(cloneRefs.GitRefs) == 0 || len(cloneRefs.GitRefs[0].Pulls) == 0 {
-		log.Println(`Clone refs do not include required SHAs. Returning "All".`)
-		tests = []string{all}
-	} else {
-		repo := prowgen.Repository{
-			Org:  cloneRefs.GitRefs[0].Org,
-			Repo: cloneRefs.GitRefs[0].Repo,
+	var paths []string
+	ok := false
+	dir := *checkout
+	force := *forceAll || ForceAll(strings.Split(*labels, ","))
+	if force {
+		log.Println(`Selecting "All" since it has been forced`)
+	} else if *checkout != "" {
+		baseRef := *base
+		if baseRef == "" && gitRefs != nil {
+			baseRef = LocalBase(*gitRefs, *previousBaseSha)
+		}
+		if baseRef != "" {
+			paths, err = LocalDiff(ctx, *checkout, baseRef, *head)
+			if err != nil {
+				log.Fatalln("Error reading diff", err)
+			}
+			ok = true
 		}
-		paths, err = Diff(ctx, repo, cloneRefs.GitRefs[0].BaseSHA, cloneRefs.GitRefs[0].Pulls[0].SHA)
+	} else if gitRefs != nil {
+		paths, ok, err = DiffRefs(ctx, *gitRefs, *previousBaseSha)
 		if err != nil {
 			log.Fatalln("Error reading diff", err)
 		}
-		tests, err = filterTests(*testSuites, paths)
+		dir = prowgen.Repository{Org: gitRefs.Org, Repo: gitRefs.Repo}.RepositoryDirectory()
+	}
+
+	var graph *GoPackageGraph
+	if ok && *goPackages {
+		graph, err = LoadGoPackageGraph(dir)
+		if err != nil {
+			log.Fatalln("Error loading Go packages", err)
+		}
+	}
+
+	var explanation *Explanation
+	if force {
+		explanation = &Explanation{
+			Reason: `Forced by the -force-all flag, the ` + ForceAllEnv + ` environment variable or the ` + ForceAllLabel + ` label, selecting "All"`,
+			Tests:  []string{all},
+		}
+	} else if !ok {
+		log.Println(`Clone refs do not include required SHAs. Returning "All".`)
+		explanation = &Explanation{
+			Reason: `Clone refs do not include required SHAs, selecting "All"`,
+			Tests:  []string{all},
+		}
+	} else {
+		explanation, err = Explain(*testSuites, paths, graph)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 
-	var sb strings.Builder
-	for _, tst := range tests {
-		sb.WriteString(tst + "\n")
+	if err := writeExplanation(explanation, *explain, *explainFormat); err != nil {
+		log.Fatal(err)
 	}
 
-	if err := os.WriteFile(*outFile, []byte(sb.String()), os.ModePerm); err != nil {
+	if *junitDir != "" {
+		durations, err := ReadJUnitDurations(*junitDir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := testSuites.WithHistoricalCosts(durations); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	selection := NewSelection(*testSuites, explanation, *expandAll)
+	if !force {
+		selection.ApplyBudget(*budget)
+	}
+	var out bytes.Buffer
+	if err := selection.Write(&out, *format); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outFile, out.Bytes(), os.ModePerm); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func Diff(ctx context.Context, repo prowgen.Repository, baseSha, sha string) ([]string, error) {
+// Diff returns the paths changed by merging the given SHAs into baseSha, batch jobs merge
+// several pulls.
+func Diff(ctx context.Context, repo prowgen.Repository, baseSha string, shas ...string) ([]string, error) {
 	if err := prowgen.GitClone(ctx, repo); err != nil {
 		return nil, err
 	}
 	if err := prowgen.GitCheckout(ctx, repo, baseSha); err != nil {
 		return nil, err
 	}
-	if err := prowgen.GitFetch(ctx, repo, sha); err != nil {
-		return nil, err
-	}
-	if err := prowgen.GitMerge(ctx, repo, sha); err != nil {
-		return nil, err
+	for _, sha := range shas {
+		if err := prowgen.GitFetch(ctx, repo, sha); err != nil {
+			return nil, err
+		}
+		// Merge FETCH_HEAD since sha might be a ref, such as pull/1/head.
+		if err := prowgen.GitMerge(ctx, repo, "FETCH_HEAD"); err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", sha, err)
+		}
 	}
 	return prowgen.GitDiffNameOnly(ctx, repo, baseSha)
 }
 
 func filterTests(testSuites TestSuites, paths []string) ([]string, error) {
+	e, err := Explain(testSuites, paths, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.Tests, nil
+}
+
+// Explain selects the tests for the given changed paths and describes why each test has been
+// selected, suites declaring Go packages are matched using the given Go package graph, if any.
+func Explain(testSuites TestSuites, paths []string, graph *GoPackageGraph) (*Explanation, error) {
+	matchers := make([]*suiteMatcher, 0, len(testSuites.List))
+	for _, suite := range testSuites.List {
+		m, err := newSuiteMatcher(suite)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	e := &Explanation{Paths: make([]PathExplanation, 0, len(paths))}
+	// matched holds, for each path, the indexes of the suites matching it.
+	matched := make([][]int, 0, len(paths))
+
+	ignore := newPatterns(testSuites.Ignore)
+	changed := make([]string, 0, len(paths))
 	testsToRun := make(map[string]bool)
+	triggered := make([]bool, len(matchers))
 	for _, path := range paths {
+		pe := PathExplanation{Path: path}
+		var suites []int
+		if p, ok := ignore.match(path); ok {
+			pe.IgnoredBy = p
+			e.Paths = append(e.Paths, pe)
+			matched = append(matched, suites)
+			continue
+		}
+		changed = append(changed, path)
+
+		var affected []string
+		if graph != nil {
+			affected = graph.Affected(path)
+		}
+
 		matchAny := false
-		for _, suite := range testSuites.List {
-			for _, pathRegex := range suite.RunIfChanged {
-				matched, err := regexp.MatchString(pathRegex, path)
-				if err != nil {
-					return nil, err
-				}
-				if matched {
-					matchAny = true
-					for _, test := range suite.Tests {
-						testsToRun[test] = true
-					}
-				}
+		for i, m := range matchers {
+			if field, expression, ok := m.triggeredBy(path, affected); ok {
+				triggered[i] = true
+				matchAny = true
+				pe.Matches = append(pe.Matches, SuiteMatch{Suite: m.suite.Name, Field: field, Expression: expression})
+				suites = append(suites, i)
+			}
+			// Paths that a suite can be skipped for are known paths.
+			if m.skippable(path) {
+				matchAny = true
 			}
 		}
 		// If the path doesn't match any path expressions then it is unknown
 		// path and all test suites should be run.
 		if !matchAny {
 			testsToRun[all] = true
+			pe.Unmatched = true
 		}
+		e.Paths = append(e.Paths, pe)
+		matched = append(matched, suites)
 	}
 
-	// Add tests that should always run.
-	for _, suite := range testSuites.List {
-		if len(suite.RunIfChanged) == 0 {
-			for _, test := range suite.Tests {
-				testsToRun[test] = true
+	selected := make([]bool, len(matchers))
+	for i, m := range matchers {
+		// Add tests that should always run and tests of triggered suites.
+		if !m.conditional() {
+			e.AlwaysRun = append(e.AlwaysRun, m.suite.Name)
+		} else if !triggered[i] {
+			continue
+		} else if m.skipped(changed) {
+			e.Skipped = append(e.Skipped, m.suite.Name)
+			continue
+		}
+		selected[i] = true
+		e.Suites = append(e.Suites, m.suite.Name)
+		for _, test := range m.suite.Tests {
+			testsToRun[test] = true
+		}
+	}
+
+	for i := range e.Paths {
+		for j, suite := range matched[i] {
+			if selected[suite] {
+				e.Paths[i].Matches[j].Tests = matchers[suite].suite.Tests
 			}
 		}
 	}
 
-	return sortedKeys(testsToRun), nil
+	e.Tests = sortedKeys(testsToRun)
+	return e, nil
 }
 
 func sortedKeys(stringMap map[string]bool) []string {
@@ -0,0 +1,207 @@
+package testselect
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift-knative/hack/pkg/junitreport"
+	"github.com/openshift/release/tools/junitreport/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	junitField = "junit"
+
+	// ForceAllEnv forces selecting every test when set to true.
+	ForceAllEnv = "TESTSELECT_FORCE_ALL"
+	// ForceAllLabel forces selecting every test when the pull request has the label.
+	ForceAllLabel = "testselect/run-all"
+
+	defaultRisk = 1.0
+)
+
+// ForceAll returns true when every test has to be selected, either because the ForceAllEnv
+// environment variable is true or because the given pull request labels include ForceAllLabel.
+func ForceAll(labels []string) bool {
+	if strings.EqualFold(os.Getenv(ForceAllEnv), "true") {
+		return true
+	}
+	return sets.NewString(labels...).Has(ForceAllLabel)
+}
+
+// ApplyBudget keeps the selected suites with the highest value, which is risk per unit of cost,
+// whose total cost fits the budget, and drops the others.
+//
+// Suites without cost are always kept, a budget lower or equal to 0 means no budget.
+// The budget isn't applied when "All" is selected and hasn't been expanded, since the cost of
+// "All" is unknown.
+func (s *Selection) ApplyBudget(budget float64) {
+	if budget <= 0 {
+		return
+	}
+	if sets.NewString(s.Tests...).Has(all) {
+		log.Println(`Budget not applied since "All" is selected, use -expand-all to apply it`)
+		return
+	}
+
+	selected := make([]int, 0, len(s.Suites))
+	for i := range s.Suites {
+		if s.Suites[i].Selected {
+			selected = append(selected, i)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return s.Suites[selected[i]].value() > s.Suites[selected[j]].value()
+	})
+
+	total := 0.0
+	for _, i := range selected {
+		suite := &s.Suites[i]
+		if total+suite.Cost > budget {
+			suite.Selected = false
+			s.Dropped = append(s.Dropped, suite.Name)
+			continue
+		}
+		total += suite.Cost
+	}
+	s.Cost = total
+	if len(s.Dropped) == 0 {
+		return
+	}
+
+	tests := sets.NewString()
+	for _, suite := range s.Suites {
+		if suite.Selected {
+			tests.Insert(suite.Tests...)
+		}
+	}
+	s.DroppedTests = sets.NewString(s.Tests...).Difference(tests).List()
+	s.Tests = tests.List()
+	// Not every test runs anymore when "All" has been expanded.
+	s.All = false
+	sort.Strings(s.Dropped)
+	log.Printf("Selected tests cost exceeds the budget %.2f, dropped suites %s, dropped tests %s", budget, strings.Join(s.Dropped, ", "), strings.Join(s.DroppedTests, ", "))
+}
+
+// value returns the risk per unit of cost of the suite, suites without cost have the highest value.
+func (s SelectedSuite) value() float64 {
+	risk := s.Risk
+	if risk <= 0 {
+		risk = defaultRisk
+	}
+	if s.Cost <= 0 {
+		return risk * 1e12
+	}
+	return risk / s.Cost
+}
+
+// ReadJUnitDurations reads the JUnit results in the given directory and returns the average
+// duration in seconds of each test case and test suite by name.
+func ReadJUnitDurations(dir string) (map[string]float64, error) {
+	durations := make(map[string][]float64)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				log.Println("Skipping unreadable path", path, err)
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".xml" {
+			return nil
+		}
+		suites, err := junitreport.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, suite := range suites {
+			addJUnitDurations(durations, suite)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JUnit results in %s: %w", dir, err)
+	}
+
+	averages := make(map[string]float64, len(durations))
+	for name, ds := range durations {
+		total := 0.0
+		for _, d := range ds {
+			total += d
+		}
+		averages[name] = total / float64(len(ds))
+	}
+	return averages, nil
+}
+
+func addJUnitDurations(durations map[string][]float64, suite *api.TestSuite) {
+	durations[suite.Name] = append(durations[suite.Name], suite.Duration)
+	for _, tc := range suite.TestCases {
+		if tc.SkipMessage != nil {
+			continue
+		}
+		durations[tc.Name] = append(durations[tc.Name], tc.Duration)
+	}
+	for _, child := range suite.Children {
+		addJUnitDurations(durations, child)
+	}
+}
+
+// WithHistoricalCosts sets the cost of suites without cost to the sum of the historical durations,
+// in minutes, of the JUnit names matching their junit expressions, or of their tests when junit
+// isn't set.
+//
+// Suites without historical durations keep their declared cost.
+func (ts *TestSuites) WithHistoricalCosts(durations map[string]float64) error {
+	names := make([]string, 0, len(durations))
+	for name := range durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i := range ts.List {
+		suite := &ts.List[i]
+		if suite.Cost > 0 {
+			continue
+		}
+
+		var matching []string
+		if len(suite.JUnit) > 0 {
+			for _, expr := range suite.JUnit {
+				r, err := regexp.Compile(expr)
+				if err != nil {
+					return fmt.Errorf("suite %q: %s %q: invalid regular expression: %w", suite.Name, junitField, expr, err)
+				}
+				for _, name := range names {
+					if r.MatchString(name) {
+						matching = append(matching, name)
+					}
+				}
+			}
+		} else {
+			for _, test := range suite.Tests {
+				if _, ok := durations[test]; ok {
+					matching = append(matching, test)
+				}
+			}
+		}
+		if len(matching) == 0 {
+			log.Printf("No historical duration for suite %q, keeping its declared cost %v", suite.Name, suite.Cost)
+			continue
+		}
+
+		seconds := 0.0
+		for _, name := range sets.NewString(matching...).List() {
+			seconds += durations[name]
+		}
+		suite.Cost = seconds / 60
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package testselect
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyBudget(t *testing.T) {
+	suites := []SelectedSuite{
+		{Name: "Unit", Tests: []string{"test-unit"}, Selected: true},
+		{Name: "E2E", Tests: []string{"test-e2e"}, Selected: true, Cost: 60, Risk: 3},
+		{Name: "Upgrade", Tests: []string{"test-upgrade"}, Selected: true, Cost: 90},
+		{Name: "Kafka", Tests: []string{"test-kafka"}, Selected: true, Cost: 30},
+		{Name: "Docs", Tests: []string{"test-docs"}, Cost: 1},
+	}
+
+	tests := []struct {
+		name      string
+		tests     []string
+		budget    float64
+		want      []string
+		wantDrop  []string
+		wantTests []string
+	}{
+		{
+			name:   "no budget",
+			tests:  []string{"test-e2e", "test-kafka", "test-unit", "test-upgrade"},
+			budget: 0,
+			want:   []string{"test-e2e", "test-kafka", "test-unit", "test-upgrade"},
+		},
+		{
+			name:      "drop lowest value",
+			tests:     []string{"test-e2e", "test-kafka", "test-unit", "test-upgrade"},
+			budget:    100,
+			want:      []string{"test-e2e", "test-kafka", "test-unit"},
+			wantDrop:  []string{"Upgrade"},
+			wantTests: []string{"test-upgrade"},
+		},
+		{
+			name:      "keep suites without cost",
+			tests:     []string{"test-e2e", "test-kafka", "test-unit", "test-upgrade"},
+			budget:    10,
+			want:      []string{"test-unit"},
+			wantDrop:  []string{"E2E", "Kafka", "Upgrade"},
+			wantTests: []string{"test-e2e", "test-kafka", "test-upgrade"},
+		},
+		{
+			name:   "All not expanded",
+			tests:  []string{"All", "test-unit"},
+			budget: 10,
+			want:   []string{"All", "test-unit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Selection{Tests: tt.tests, Suites: append([]SelectedSuite(nil), suites...)}
+			s.ApplyBudget(tt.budget)
+			if diff := cmp.Diff(tt.want, s.Tests); diff != "" {
+				t.Errorf("Unexpected tests (-want, +got): \n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantDrop, s.Dropped); diff != "" {
+				t.Errorf("Unexpected dropped suites (-want, +got): \n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantTests, s.DroppedTests); diff != "" {
+				t.Errorf("Unexpected dropped tests (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyBudgetExpandAll(t *testing.T) {
+	ts := TestSuites{
+		List: []TestSuite{
+			{Name: "Unit", Tests: []string{"test-unit"}},
+			{Name: "E2E", RunIfChanged: []string{"^eventing/"}, Tests: []string{"test-e2e"}, Cost: 60},
+			{Name: "Upgrade", RunIfChanged: []string{"^upgrade/"}, Tests: []string{"test-upgrade"}, Cost: 90},
+		},
+	}
+	explanation, err := Explain(ts, []string{"hack/lib.sh"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSelection(ts, explanation, true)
+	s.ApplyBudget(100)
+
+	// Tests dropped by the budget don't run, even though "All" has been selected.
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: MakeOutputFormat,
+			want: `TESTSELECT_TESTS := test-e2e test-unit
+TESTSELECT_ALL := false
+TESTSELECT_TEST_E2E := true
+TESTSELECT_TEST_UNIT := true
+TESTSELECT_TEST_UPGRADE := false
+`,
+		},
+		{
+			format: JUnitOutputFormat,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testselect" tests="3" skipped="1" failures="0" time="0">
+    <testcase name="test-e2e" time="0"></testcase>
+    <testcase name="test-unit" time="0"></testcase>
+    <testcase name="test-upgrade" time="0">
+      <skipped message="not selected"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := s.Write(&out, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("Unexpected output (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadJUnitDurations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"junit_1.xml": `<testsuites>
+  <testsuite name="test-e2e" time="120" tests="2">
+    <testcase name="TestBroker" time="100"></testcase>
+    <testcase name="TestSkipped" time="0"><skipped message="skip"></skipped></testcase>
+  </testsuite>
+</testsuites>`,
+		"nested/junit_2.xml": `<testsuite name="test-e2e" time="60" tests="1">
+  <testcase name="TestBroker" time="50"></testcase>
+  <testsuite name="test-upgrade" time="30" tests="0"></testsuite>
+</testsuite>`,
+		"build-log.txt": "not JUnit",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	durations, err := ReadJUnitDurations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"test-e2e":     90,
+		"TestBroker":   75,
+		"test-upgrade": 30,
+	}
+	if diff := cmp.Diff(want, durations); diff != "" {
+		t.Errorf("Unexpected durations (-want, +got): \n%s", diff)
+	}
+
+	ts := TestSuites{List: []TestSuite{
+		{Name: "E2E", Tests: []string{"test-e2e", "test-upgrade"}},
+		{Name: "Unit", Tests: []string{"test-unit"}, Cost: 5},
+	}}
+	if err := ts.WithHistoricalCosts(durations); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]float64{2, 5}, []float64{ts.List[0].Cost, ts.List[1].Cost}); diff != "" {
+		t.Errorf("Unexpected costs (-want, +got): \n%s", diff)
+	}
+}
+
+func TestWithHistoricalCosts(t *testing.T) {
+	// JUnit names are Go test names, not the Make targets running them.
+	durations := map[string]float64{
+		"eventing-e2e":    900,
+		"TestBroker":      600,
+		"TestTrigger":     300,
+		"TestUpgrade":     1200,
+		"TestKafkaSource": 120,
+	}
+
+	tests := []struct {
+		name    string
+		suite   TestSuite
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:  "junit test cases",
+			suite: TestSuite{Name: "E2E", Tests: []string{"test-e2e"}, JUnit: []string{"^TestBroker$", "^TestTrigger$"}},
+			want:  15,
+		},
+		{
+			name:  "junit test suite",
+			suite: TestSuite{Name: "E2E", Tests: []string{"test-e2e"}, JUnit: []string{"^eventing-e2e$"}},
+			want:  15,
+		},
+		{
+			name:  "overlapping junit expressions",
+			suite: TestSuite{Name: "Kafka", Tests: []string{"test-kafka"}, JUnit: []string{"^TestKafka", "Source$"}},
+			want:  2,
+		},
+		{
+			name:  "tests without history keep the declared cost",
+			suite: TestSuite{Name: "Upgrade", Tests: []string{"test-upgrade"}},
+			want:  0,
+		},
+		{
+			name:  "junit without history keeps the declared cost",
+			suite: TestSuite{Name: "Upgrade", Tests: []string{"test-upgrade"}, JUnit: []string{"^TestDowngrade$"}},
+			want:  0,
+		},
+		{
+			name:  "declared cost",
+			suite: TestSuite{Name: "Upgrade", Tests: []string{"test-upgrade"}, JUnit: []string{"^TestUpgrade$"}, Cost: 10},
+			want:  10,
+		},
+		{
+			name:    "invalid junit expression",
+			suite:   TestSuite{Name: "Upgrade", Tests: []string{"test-upgrade"}, JUnit: []string{"(TestUpgrade"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := TestSuites{List: []TestSuite{tt.suite}}
+			err := ts.WithHistoricalCosts(durations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, ts.List[0].Cost); diff != "" {
+				t.Errorf("Unexpected cost (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package testselect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	TextExplainFormat = "text"
+	JSONExplainFormat = "json"
+)
+
+// Explanation describes why each test has been selected.
+type Explanation struct {
+	// Reason is set when tests have been selected without looking at changed paths, for example,
+	// when clone refs don't include the required SHAs.
+	Reason string            `json:"reason,omitempty"`
+	Paths  []PathExplanation `json:"paths"`
+	// AlwaysRun lists suites without conditions, which always run.
+	AlwaysRun []string `json:"alwaysRun,omitempty"`
+	// Skipped lists suites skipped since only paths matching their skip_if_only_changed changed.
+	Skipped []string `json:"skipped,omitempty"`
+	// Suites lists the selected suites.
+	Suites []string `json:"suites,omitempty"`
+	Tests  []string `json:"tests"`
+}
+
+// PathExplanation describes how a changed path has been matched.
+type PathExplanation struct {
+	Path string `json:"path"`
+	// IgnoredBy is the global ignore pattern matching the path, if any.
+	IgnoredBy string       `json:"ignoredBy,omitempty"`
+	Matches   []SuiteMatch `json:"matches,omitempty"`
+	// Unmatched is true when no suite matches the path, which selects All.
+	Unmatched bool `json:"unmatched,omitempty"`
+}
+
+// SuiteMatch describes a suite matching a changed path.
+type SuiteMatch struct {
+	Suite string `json:"suite"`
+	// Field is the suite field matching the path, such as run_if_changed.
+	Field string `json:"field"`
+	// Expression is the regular expression or pattern matching the path, it is empty for suites
+	// with only skip_if_only_changed, which are triggered by paths not matching them.
+	Expression string   `json:"expression,omitempty"`
+	Tests      []string `json:"tests,omitempty"`
+}
+
+// writeExplanation writes the explanation to the given path, - writes it to stdout, empty
+// doesn't write it.
+func writeExplanation(e *Explanation, path string, format string) error {
+	if path == "" {
+		return nil
+	}
+	if path == "-" {
+		return e.Write(os.Stdout, format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create explanation file %s: %w", path, err)
+	}
+	if err := e.Write(f, format); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the explanation in the given format, text or json.
+func (e *Explanation) Write(w io.Writer, format string) error {
+	switch format {
+	case JSONExplainFormat:
+		out, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal explanation: %w", err)
+		}
+		_, err = w.Write(append(out, '\n'))
+		return err
+	case TextExplainFormat, "":
+		_, err := io.WriteString(w, e.String())
+		return err
+	default:
+		return fmt.Errorf("unknown explain format %q, supported formats: %s, %s", format, TextExplainFormat, JSONExplainFormat)
+	}
+}
+
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	if e.Reason != "" {
+		fmt.Fprintf(&sb, "%s\n", e.Reason)
+	}
+	for _, p := range e.Paths {
+		switch {
+		case p.IgnoredBy != "":
+			fmt.Fprintf(&sb, "%s: ignored by %q\n", p.Path, p.IgnoredBy)
+		case p.Unmatched:
+			fmt.Fprintf(&sb, "%s: not matched by any suite, selecting %s\n", p.Path, all)
+		case len(p.Matches) == 0:
+			fmt.Fprintf(&sb, "%s: only matched by %s\n", p.Path, skipIfOnlyChangedField)
+		default:
+			fmt.Fprintf(&sb, "%s:\n", p.Path)
+			for _, m := range p.Matches {
+				expression := ""
+				if m.Expression != "" {
+					expression = fmt.Sprintf(" %q", m.Expression)
+				}
+				tests := "no tests"
+				if len(m.Tests) > 0 {
+					tests = strings.Join(m.Tests, ", ")
+				}
+				fmt.Fprintf(&sb, "  suite %q %s%s: %s\n", m.Suite, m.Field, expression, tests)
+			}
+		}
+	}
+	for _, suite := range e.AlwaysRun {
+		fmt.Fprintf(&sb, "suite %q always runs\n", suite)
+	}
+	for _, suite := range e.Skipped {
+		fmt.Fprintf(&sb, "suite %q skipped, only paths matching %s changed\n", suite, skipIfOnlyChangedField)
+	}
+	fmt.Fprintf(&sb, "selected tests: %s\n", strings.Join(e.Tests, ", "))
+	return sb.String()
+}
@@ -0,0 +1,151 @@
+package testselect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExplain(t *testing.T) {
+	ts := TestSuites{
+		Ignore: []string{"docs/"},
+		List: []TestSuite{
+			{
+				Name:  "Run Always",
+				Tests: []string{"serverless_operator_e2e_tests"},
+			},
+			{
+				Name:         "Run Eventing",
+				RunIfChanged: []string{"^knative-operator/pkg/webhook/knativeeventing/"},
+				Tests:        []string{"downstream_eventing_e2e_tests"},
+			},
+			{
+				Name:                 "Run Kafka",
+				RunIfChangedPatterns: []string{"knativekafka/"},
+				SkipIfOnlyChanged:    []string{"*.yaml"},
+				Tests:                []string{"serverless_operator_kafka_e2e_tests"},
+			},
+		},
+	}
+
+	e, err := Explain(ts, []string{
+		"docs/mesh.md",
+		"knative-operator/pkg/webhook/knativeeventing/webhook_mutating.go",
+		"knative-operator/pkg/webhook/knativekafka/config.yaml",
+		"hack/lib/serverless.bash",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Explanation{
+		Paths: []PathExplanation{
+			{
+				Path:      "docs/mesh.md",
+				IgnoredBy: "docs/",
+			},
+			{
+				Path: "knative-operator/pkg/webhook/knativeeventing/webhook_mutating.go",
+				Matches: []SuiteMatch{
+					{
+						Suite:      "Run Eventing",
+						Field:      runIfChangedField,
+						Expression: "^knative-operator/pkg/webhook/knativeeventing/",
+						Tests:      []string{"downstream_eventing_e2e_tests"},
+					},
+				},
+			},
+			{
+				Path: "knative-operator/pkg/webhook/knativekafka/config.yaml",
+				Matches: []SuiteMatch{
+					{
+						Suite:      "Run Kafka",
+						Field:      runIfChangedPatternsField,
+						Expression: "knativekafka/",
+						Tests:      []string{"serverless_operator_kafka_e2e_tests"},
+					},
+				},
+			},
+			{
+				Path:      "hack/lib/serverless.bash",
+				Unmatched: true,
+			},
+		},
+		AlwaysRun: []string{"Run Always"},
+		Suites:    []string{"Run Always", "Run Eventing", "Run Kafka"},
+		Tests: []string{
+			"All",
+			"downstream_eventing_e2e_tests",
+			"serverless_operator_e2e_tests",
+			"serverless_operator_kafka_e2e_tests",
+		},
+	}
+	if diff := cmp.Diff(want, e); diff != "" {
+		t.Fatalf("Unexpected explanation (-want, +got): \n%s", diff)
+	}
+
+	var out bytes.Buffer
+	if err := e.Write(&out, JSONExplainFormat); err != nil {
+		t.Fatal(err)
+	}
+	got := &Explanation{}
+	if err := json.Unmarshal(out.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Unexpected JSON explanation (-want, +got): \n%s", diff)
+	}
+
+	wantText := `docs/mesh.md: ignored by "docs/"
+knative-operator/pkg/webhook/knativeeventing/webhook_mutating.go:
+  suite "Run Eventing" run_if_changed "^knative-operator/pkg/webhook/knativeeventing/": downstream_eventing_e2e_tests
+knative-operator/pkg/webhook/knativekafka/config.yaml:
+  suite "Run Kafka" run_if_changed_patterns "knativekafka/": serverless_operator_kafka_e2e_tests
+hack/lib/serverless.bash: not matched by any suite, selecting All
+suite "Run Always" always runs
+selected tests: All, downstream_eventing_e2e_tests, serverless_operator_e2e_tests, serverless_operator_kafka_e2e_tests
+`
+	if diff := cmp.Diff(wantText, e.String()); diff != "" {
+		t.Fatalf("Unexpected text explanation (-want, +got): \n%s", diff)
+	}
+}
+
+func TestExplainSkipped(t *testing.T) {
+	ts := TestSuites{
+		List: []TestSuite{
+			{
+				Name:              "Run Kafka",
+				RunIfChanged:      []string{"knativekafka/"},
+				SkipIfOnlyChanged: []string{"*.yaml"},
+				Tests:             []string{"serverless_operator_kafka_e2e_tests"},
+			},
+		},
+	}
+
+	e, err := Explain(ts, []string{"knative-operator/pkg/webhook/knativekafka/config.yaml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Explanation{
+		Paths: []PathExplanation{
+			{
+				Path: "knative-operator/pkg/webhook/knativekafka/config.yaml",
+				Matches: []SuiteMatch{
+					{Suite: "Run Kafka", Field: runIfChangedField, Expression: "knativekafka/"},
+				},
+			},
+		},
+		Skipped: []string{"Run Kafka"},
+		Tests:   []string{},
+	}
+	if diff := cmp.Diff(want, e); diff != "" {
+		t.Fatalf("Unexpected explanation (-want, +got): \n%s", diff)
+	}
+
+	if err := e.Write(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
@@ -0,0 +1,168 @@
+package testselect
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const packagesField = "packages"
+
+// GoPackageGraph is the import graph of the Go packages of a checkout, including test imports.
+type GoPackageGraph struct {
+	// files maps checkout relative files to the import paths of the packages they belong to.
+	files map[string]sets.String
+	// dirs maps checkout relative directories to the import paths of the packages in them.
+	dirs map[string]sets.String
+	// importedBy maps an import path to the import paths of the packages importing it.
+	importedBy map[string]sets.String
+	all        sets.String
+}
+
+// LoadGoPackageGraph loads the import graph of the Go packages in the given checkout directory.
+func LoadGoPackageGraph(dir string) (*GoPackageGraph, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %s: %w", dir, err)
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedEmbedFiles | packages.NeedImports,
+		Dir:   root,
+		Tests: true,
+	}, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("[%s] failed to load Go packages: %w", dir, err)
+	}
+
+	g := &GoPackageGraph{
+		files:      make(map[string]sets.String),
+		dirs:       make(map[string]sets.String),
+		importedBy: make(map[string]sets.String),
+		all:        sets.NewString(),
+	}
+	for _, pkg := range pkgs {
+		// Skip generated test main packages.
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		for _, e := range pkg.Errors {
+			log.Println(dir, "Go package", pkg.ID, "has errors", e)
+		}
+
+		importPath := packageImportPath(pkg)
+		g.all.Insert(importPath)
+
+		files := make([]string, 0, len(pkg.GoFiles)+len(pkg.OtherFiles)+len(pkg.IgnoredFiles)+len(pkg.EmbedFiles))
+		files = append(files, pkg.GoFiles...)
+		files = append(files, pkg.OtherFiles...)
+		files = append(files, pkg.IgnoredFiles...)
+		files = append(files, pkg.EmbedFiles...)
+		for _, f := range files {
+			rel, err := filepath.Rel(root, f)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			insert(g.files, rel, importPath)
+			insert(g.dirs, path.Dir(rel), importPath)
+		}
+
+		for imp := range pkg.Imports {
+			insert(g.importedBy, imp, importPath)
+		}
+	}
+	return g, nil
+}
+
+// packageImportPath returns the import path of the given package, test variants and external
+// test packages are mapped to the package they test.
+func packageImportPath(pkg *packages.Package) string {
+	if strings.HasSuffix(pkg.Name, "_test") {
+		return strings.TrimSuffix(pkg.PkgPath, "_test")
+	}
+	return pkg.PkgPath
+}
+
+func insert(m map[string]sets.String, key string, value string) {
+	if _, ok := m[key]; !ok {
+		m[key] = sets.NewString()
+	}
+	m[key].Insert(value)
+}
+
+// Affected returns the sorted import paths of the packages the given checkout relative path
+// belongs to and of every package transitively depending on them.
+//
+// Files that aren't part of a package, such as testdata, belong to the package of the closest
+// parent directory, excluding the checkout root, vendored files belong to the vendored package,
+// and go.mod and go.sum affect every package.
+func (g *GoPackageGraph) Affected(changed string) []string {
+	changed = path.Clean(filepath.ToSlash(changed))
+
+	if base := path.Base(changed); path.Dir(changed) == "." && (base == "go.mod" || base == "go.sum") {
+		return g.all.List()
+	}
+
+	seeds := sets.NewString()
+	if vendored := strings.TrimPrefix(changed, "vendor/"); vendored != changed {
+		seeds.Insert(path.Dir(vendored))
+	} else if pkgs, ok := g.files[changed]; ok {
+		seeds = seeds.Union(pkgs)
+	} else {
+		// The checkout root is excluded, root files such as README.md or Makefile don't belong
+		// to the root package.
+		for dir := path.Dir(changed); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if pkgs, ok := g.dirs[dir]; ok {
+				seeds = seeds.Union(pkgs)
+				break
+			}
+		}
+	}
+
+	affected := sets.NewString()
+	queue := seeds.List()
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		if affected.Has(pkg) {
+			continue
+		}
+		affected.Insert(pkg)
+		queue = append(queue, g.importedBy[pkg].List()...)
+	}
+	if affected.Len() == 0 {
+		return nil
+	}
+	return affected.List()
+}
+
+// matchPackage returns true when the given import path matches the given pattern, which is an
+// import path, optionally ending with /... to match every package under it.
+func matchPackage(pattern string, importPath string) bool {
+	if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+		return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+	}
+	return importPath == pattern
+}
+
+// matchPackages returns the first pattern matching one of the given import paths, and the
+// matching import path.
+func matchPackages(patterns []string, importPaths []string) (string, string, bool) {
+	sorted := append([]string{}, importPaths...)
+	sort.Strings(sorted)
+	for _, pattern := range patterns {
+		for _, importPath := range sorted {
+			if matchPackage(pattern, importPath) {
+				return pattern, importPath, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,149 @@
+package testselect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGoPackageGraph(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                            "module example.com/m\n\ngo 1.18\n",
+		"doc.go":                            "package m\n",
+		"README.md":                         "# m\n",
+		"hack/lib.sh":                       "#!/usr/bin/env bash\n",
+		"docs/guide/install.md":             "# Install\n",
+		"pkg/apis/types.go":                 "package apis\n\ntype Broker struct{}\n",
+		"pkg/reconciler/broker.go":          "package reconciler\n\nimport _ \"example.com/m/pkg/apis\"\n",
+		"pkg/reconciler/testdata/cm.yaml":   "data: {}\n",
+		"pkg/reconciler/testdata/cm/a.yaml": "data: {}\n",
+		"pkg/other/other.go":                "package other\n",
+		"pkg/other/other_test.go":           "package other_test\n\nimport _ \"example.com/m/pkg/apis\"\n",
+		"cmd/controller/main.go":            "package main\n\nimport _ \"example.com/m/pkg/reconciler\"\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := LoadGoPackageGraph(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{
+			path: "pkg/apis/types.go",
+			want: []string{"example.com/m/cmd/controller", "example.com/m/pkg/apis", "example.com/m/pkg/other", "example.com/m/pkg/reconciler"},
+		},
+		{
+			path: "pkg/reconciler/testdata/cm.yaml",
+			want: []string{"example.com/m/cmd/controller", "example.com/m/pkg/reconciler"},
+		},
+		{
+			path: "pkg/other/other_test.go",
+			want: []string{"example.com/m/pkg/other"},
+		},
+		{
+			path: "go.mod",
+			want: []string{"example.com/m", "example.com/m/cmd/controller", "example.com/m/pkg/apis", "example.com/m/pkg/other", "example.com/m/pkg/reconciler"},
+		},
+		{
+			path: "pkg/reconciler/testdata/cm/a.yaml",
+			want: []string{"example.com/m/cmd/controller", "example.com/m/pkg/reconciler"},
+		},
+		{
+			path: "go.sum",
+			want: []string{"example.com/m", "example.com/m/cmd/controller", "example.com/m/pkg/apis", "example.com/m/pkg/other", "example.com/m/pkg/reconciler"},
+		},
+		// Non-Go files outside of package directories don't belong to the root package.
+		{
+			path: "README.md",
+		},
+		{
+			path: "hack/lib.sh",
+		},
+		{
+			path: "docs/guide/install.md",
+		},
+		{
+			path: "doc.go",
+			want: []string{"example.com/m"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, g.Affected(tt.path)); diff != "" {
+				t.Errorf("Unexpected packages (-want, +got): \n%s", diff)
+			}
+		})
+	}
+
+	ts := TestSuites{
+		List: []TestSuite{
+			{
+				Name:     "Run controller",
+				Packages: []string{"example.com/m/cmd/..."},
+				Tests:    []string{"test-e2e"},
+			},
+			{
+				Name:     "Run other",
+				Packages: []string{"example.com/m/pkg/other"},
+				Tests:    []string{"test-other"},
+			},
+		},
+	}
+	e, err := Explain(ts, []string{"pkg/reconciler/broker.go"}, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PathExplanation{
+		{
+			Path: "pkg/reconciler/broker.go",
+			Matches: []SuiteMatch{
+				{
+					Suite:      "Run controller",
+					Field:      packagesField,
+					Expression: "example.com/m/cmd/... (example.com/m/cmd/controller)",
+					Tests:      []string{"test-e2e"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, e.Paths); diff != "" {
+		t.Errorf("Unexpected explanation (-want, +got): \n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"test-e2e"}, e.Tests); diff != "" {
+		t.Errorf("Unexpected tests (-want, +got): \n%s", diff)
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		importPath string
+		want       bool
+	}{
+		{pattern: "example.com/m/pkg/apis", importPath: "example.com/m/pkg/apis", want: true},
+		{pattern: "example.com/m/pkg/apis", importPath: "example.com/m/pkg/apis/v1", want: false},
+		{pattern: "example.com/m/pkg/apis/...", importPath: "example.com/m/pkg/apis", want: true},
+		{pattern: "example.com/m/pkg/apis/...", importPath: "example.com/m/pkg/apis/v1", want: true},
+		{pattern: "example.com/m/pkg/apis/...", importPath: "example.com/m/pkg/apisv2", want: false},
+	}
+	for _, tt := range tests {
+		if got := matchPackage(tt.pattern, tt.importPath); got != tt.want {
+			t.Errorf("matchPackage(%q, %q) = %v, want %v", tt.pattern, tt.importPath, got, tt.want)
+		}
+	}
+}
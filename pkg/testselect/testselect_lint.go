@@ -0,0 +1,278 @@
+package testselect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LintReport reports the coverage of a repository by test suites.
+type LintReport struct {
+	// Uncovered lists files no suite covers, changing them selects All.
+	Uncovered []string `json:"uncovered"`
+	// Unused lists expressions matching no file.
+	Unused []UnusedExpression `json:"unused"`
+	// Overlaps lists suites triggered by the same files.
+	Overlaps []SuitesOverlap `json:"overlaps"`
+}
+
+// UnusedExpression is an expression matching no file.
+type UnusedExpression struct {
+	// Suite is empty for the global ignore list.
+	Suite      string `json:"suite,omitempty"`
+	Field      string `json:"field"`
+	Expression string `json:"expression"`
+}
+
+// SuitesOverlap describes files triggering two suites.
+type SuitesOverlap struct {
+	Suites []string `json:"suites"`
+	Files  []string `json:"files"`
+}
+
+// LintMain runs `testselect lint` with the given arguments and returns the exit code.
+func LintMain(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	ts := flags.String("testsuites", "testsuites.yaml", "Specify yaml file with path-to-testsuite mapping")
+	dir := flags.String("dir", ".", "Repository checkout to lint")
+	walk := flags.Bool("walk", false, "Walk the repository tree instead of listing files with git ls-files")
+	goPackages := flags.Bool("go-packages", false, "Load the Go package graph of the checkout to match suites declaring Go packages")
+	format := flags.String("format", TextExplainFormat, "Format of the report: text or json")
+	strict := flags.Bool("strict", false, "Fail on expressions matching no file too")
+	_ = flags.Parse(args)
+
+	testSuites, err := ReadTestSuites(*ts)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+
+	var files []string
+	if *walk {
+		files, err = walkFiles(*dir)
+	} else {
+		files, err = gitFiles(ctx, *dir)
+	}
+	if err != nil {
+		log.Println("Error listing files", err)
+		return 2
+	}
+
+	var graph *GoPackageGraph
+	if *goPackages {
+		graph, err = LoadGoPackageGraph(*dir)
+		if err != nil {
+			log.Println("Error loading Go packages", err)
+			return 2
+		}
+	}
+
+	report, err := Lint(*testSuites, files, graph)
+	if err != nil {
+		log.Println(err)
+		return 2
+	}
+	if err := report.Write(os.Stdout, *format); err != nil {
+		log.Println(err)
+		return 2
+	}
+
+	if len(report.Uncovered) > 0 || (*strict && len(report.Unused) > 0) {
+		return 1
+	}
+	return 0
+}
+
+// Lint reports the files of the repository no suite covers, expressions matching no file and
+// overlaps between suites.
+//
+// Package expressions are verified only when the Go package graph is given.
+func Lint(testSuites TestSuites, files []string, graph *GoPackageGraph) (*LintReport, error) {
+	e, err := Explain(testSuites, files, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{
+		Uncovered: []string{},
+		Unused:    []UnusedExpression{},
+		Overlaps:  []SuitesOverlap{},
+	}
+
+	overlaps := make(map[[2]string][]string)
+	for _, p := range e.Paths {
+		if p.Unmatched {
+			report.Uncovered = append(report.Uncovered, p.Path)
+		}
+		for i := range p.Matches {
+			for j := i + 1; j < len(p.Matches); j++ {
+				key := [2]string{p.Matches[i].Suite, p.Matches[j].Suite}
+				overlaps[key] = append(overlaps[key], p.Path)
+			}
+		}
+	}
+	for key, paths := range overlaps {
+		report.Overlaps = append(report.Overlaps, SuitesOverlap{Suites: []string{key[0], key[1]}, Files: paths})
+	}
+	sort.Slice(report.Overlaps, func(i, j int) bool {
+		return strings.Join(report.Overlaps[i].Suites, "/") < strings.Join(report.Overlaps[j].Suites, "/")
+	})
+
+	for _, p := range unusedPatterns(newPatterns(testSuites.Ignore), files) {
+		report.Unused = append(report.Unused, UnusedExpression{Field: ignoreField, Expression: p})
+	}
+	for _, suite := range testSuites.List {
+		m, err := newSuiteMatcher(suite)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range m.runIfChanged {
+			used := false
+			for _, f := range files {
+				if r.MatchString(f) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				report.Unused = append(report.Unused, UnusedExpression{Suite: suite.Name, Field: runIfChangedField, Expression: r.String()})
+			}
+		}
+		for _, p := range unusedPatterns(m.runIfChangedPatterns, files) {
+			report.Unused = append(report.Unused, UnusedExpression{Suite: suite.Name, Field: runIfChangedPatternsField, Expression: p})
+		}
+		for _, p := range unusedPatterns(m.skipIfOnlyChanged, files) {
+			report.Unused = append(report.Unused, UnusedExpression{Suite: suite.Name, Field: skipIfOnlyChangedField, Expression: p})
+		}
+		if graph != nil {
+			for _, pattern := range suite.Packages {
+				if _, _, ok := matchPackages([]string{pattern}, graph.all.List()); !ok {
+					report.Unused = append(report.Unused, UnusedExpression{Suite: suite.Name, Field: packagesField, Expression: pattern})
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// unusedPatterns returns the patterns, excluding negated patterns, matching no file.
+func unusedPatterns(ps patterns, files []string) []string {
+	var unused []string
+	for _, p := range ps {
+		if strings.HasPrefix(p.raw, "!") {
+			continue
+		}
+		used := false
+		for _, f := range files {
+			if _, ok := (patterns{p}).match(f); ok {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, p.raw)
+		}
+	}
+	return unused
+}
+
+// Write writes the report in the given format, text or json.
+func (r *LintReport) Write(w io.Writer, format string) error {
+	switch format {
+	case JSONExplainFormat:
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal lint report: %w", err)
+		}
+		_, err = w.Write(append(out, '\n'))
+		return err
+	case TextExplainFormat, "":
+		_, err := io.WriteString(w, r.String())
+		return err
+	default:
+		return fmt.Errorf("unknown lint format %q, supported formats: %s, %s", format, TextExplainFormat, JSONExplainFormat)
+	}
+}
+
+func (r *LintReport) String() string {
+	var sb strings.Builder
+	if len(r.Uncovered) > 0 {
+		fmt.Fprintf(&sb, "Files not covered by any suite (%d), changing them selects %s:\n", len(r.Uncovered), all)
+		for _, f := range r.Uncovered {
+			fmt.Fprintf(&sb, "  %s\n", f)
+		}
+	}
+	if len(r.Unused) > 0 {
+		fmt.Fprintf(&sb, "Expressions matching no file (%d):\n", len(r.Unused))
+		for _, u := range r.Unused {
+			if u.Suite == "" {
+				fmt.Fprintf(&sb, "  %s %q\n", u.Field, u.Expression)
+			} else {
+				fmt.Fprintf(&sb, "  suite %q %s %q\n", u.Suite, u.Field, u.Expression)
+			}
+		}
+	}
+	if len(r.Overlaps) > 0 {
+		fmt.Fprintf(&sb, "Overlapping suites (%d):\n", len(r.Overlaps))
+		for _, o := range r.Overlaps {
+			fmt.Fprintf(&sb, "  suites %q and %q: %d files, for example, %s\n", o.Suites[0], o.Suites[1], len(o.Files), o.Files[0])
+		}
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("Every file is covered by a suite\n")
+	}
+	return sb.String()
+}
+
+// gitFiles lists the files tracked by git in the given checkout.
+func gitFiles(ctx context.Context, dir string) ([]string, error) {
+	out, err := localGit(ctx, dir, "ls-files")
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// walkFiles lists the files in the given directory, excluding .git.
+func walkFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				log.Println("Skipping unreadable path", path, err)
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,93 @@
+package testselect
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLint(t *testing.T) {
+	ts := TestSuites{
+		Ignore: []string{"docs/", "OWNERS_ALIASES"},
+		List: []TestSuite{
+			{
+				Name:         "Run Eventing",
+				RunIfChanged: []string{"^pkg/", "^unknown/"},
+				Tests:        []string{"test-e2e"},
+			},
+			{
+				Name:                 "Run Reconciler",
+				RunIfChangedPatterns: []string{"pkg/reconciler/", "!*_test.go", "legacy/"},
+				Tests:                []string{"test-reconciler"},
+			},
+		},
+	}
+	files := []string{
+		"docs/README.md",
+		"hack/update-codegen.sh",
+		"pkg/apis/types.go",
+		"pkg/reconciler/broker.go",
+		"pkg/reconciler/broker_test.go",
+	}
+
+	got, err := Lint(ts, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &LintReport{
+		Uncovered: []string{"hack/update-codegen.sh"},
+		Unused: []UnusedExpression{
+			{Field: ignoreField, Expression: "OWNERS_ALIASES"},
+			{Suite: "Run Eventing", Field: runIfChangedField, Expression: "^unknown/"},
+			{Suite: "Run Reconciler", Field: runIfChangedPatternsField, Expression: "legacy/"},
+		},
+		Overlaps: []SuitesOverlap{
+			{Suites: []string{"Run Eventing", "Run Reconciler"}, Files: []string{"pkg/reconciler/broker.go"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Unexpected lint report (-want, +got): \n%s", diff)
+	}
+
+	wantText := `Files not covered by any suite (1), changing them selects All:
+  hack/update-codegen.sh
+Expressions matching no file (3):
+  ignore "OWNERS_ALIASES"
+  suite "Run Eventing" run_if_changed "^unknown/"
+  suite "Run Reconciler" run_if_changed_patterns "legacy/"
+Overlapping suites (1):
+  suites "Run Eventing" and "Run Reconciler": 1 files, for example, pkg/reconciler/broker.go
+`
+	var out bytes.Buffer
+	if err := got.Write(&out, TextExplainFormat); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantText, out.String()); diff != "" {
+		t.Fatalf("Unexpected text lint report (-want, +got): \n%s", diff)
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{".git/HEAD", "pkg/apis/types.go", "Makefile"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := walkFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"Makefile", "pkg/apis/types.go"}, got); diff != "" {
+		t.Fatalf("Unexpected files (-want, +got): \n%s", diff)
+	}
+}
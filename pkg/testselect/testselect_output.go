@@ -0,0 +1,205 @@
+package testselect
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/release/tools/junitreport/pkg/api"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	TextOutputFormat   = "text"
+	JSONOutputFormat   = "json"
+	ShellOutputFormat  = "shell"
+	MakeOutputFormat   = "make"
+	JUnitOutputFormat  = "junit"
+	GitHubOutputFormat = "github"
+
+	// variablePrefix prefixes shell, Make and GitHub output variables.
+	variablePrefix = "TESTSELECT_"
+)
+
+var nonVariableCharRegex = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// Selection is the result of the test selection.
+type Selection struct {
+	// Tests are the selected tests, they include "All" unless it has been expanded.
+	Tests []string `json:"tests"`
+	// All is true when every test has to run, for example, when a changed path doesn't match
+	// any suite, it's reset when the budget drops suites.
+	All    bool            `json:"all"`
+	Suites []SelectedSuite `json:"suites"`
+	// Cost is the total cost of the selected suites when a budget is applied.
+	Cost float64 `json:"cost,omitempty"`
+	// Dropped lists the suites dropped to fit the budget.
+	Dropped []string `json:"dropped,omitempty"`
+	// DroppedTests lists the tests dropped to fit the budget.
+	DroppedTests []string `json:"droppedTests,omitempty"`
+}
+
+// SelectedSuite describes a suite and whether it has been selected.
+type SelectedSuite struct {
+	Name     string   `json:"name"`
+	Tests    []string `json:"tests"`
+	Selected bool     `json:"selected"`
+	Cost     float64  `json:"cost,omitempty"`
+	Risk     float64  `json:"risk,omitempty"`
+}
+
+// NewSelection creates the selection for the given explanation, when expandAll is true, "All" is
+// replaced by the tests of every suite.
+func NewSelection(testSuites TestSuites, e *Explanation, expandAll bool) *Selection {
+	selectedSuites := sets.NewString(e.Suites...)
+	tests := sets.NewString(e.Tests...)
+	s := &Selection{
+		All:    tests.Has(all),
+		Suites: make([]SelectedSuite, 0, len(testSuites.List)),
+	}
+	if s.All && expandAll {
+		tests.Delete(all)
+	}
+	for _, suite := range testSuites.List {
+		selected := selectedSuites.Has(suite.Name) || (s.All && expandAll)
+		if selected && s.All && expandAll {
+			tests.Insert(suite.Tests...)
+		}
+		s.Suites = append(s.Suites, SelectedSuite{Name: suite.Name, Tests: suite.Tests, Selected: selected, Cost: suite.Cost, Risk: suite.Risk})
+	}
+	s.Tests = tests.List()
+	return s
+}
+
+// knownTests returns every test of every suite and the selected tests, excluding "All".
+func (s *Selection) knownTests() []string {
+	known := sets.NewString(s.Tests...)
+	for _, suite := range s.Suites {
+		known.Insert(suite.Tests...)
+	}
+	return known.Delete(all).List()
+}
+
+// Write writes the selection in the given format:
+//   - text: one test per line,
+//   - json: the selection with suite metadata,
+//   - shell: a sourceable file with a boolean variable per test,
+//   - make: a Makefile fragment with a boolean variable per test,
+//   - junit: a JUnit report with a test case per test, not selected tests are skipped,
+//   - github: GitHub Actions step outputs, to be appended to $GITHUB_OUTPUT.
+func (s *Selection) Write(w io.Writer, format string) error {
+	var out string
+	switch format {
+	case TextOutputFormat, "":
+		for _, test := range s.Tests {
+			out += test + "\n"
+		}
+	case JSONOutputFormat:
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal selection: %w", err)
+		}
+		out = string(b) + "\n"
+	case ShellOutputFormat:
+		vars, err := s.variables(func(name, value string) string {
+			return fmt.Sprintf("%s=%s\n", name, shellQuote(value))
+		})
+		if err != nil {
+			return err
+		}
+		out = vars
+	case MakeOutputFormat:
+		vars, err := s.variables(func(name, value string) string {
+			return fmt.Sprintf("%s := %s\n", name, value)
+		})
+		if err != nil {
+			return err
+		}
+		out = vars
+	case GitHubOutputFormat:
+		tests, err := json.Marshal(s.Tests)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tests: %w", err)
+		}
+		vars, err := s.variables(func(name, value string) string {
+			if name == variablePrefix+"TESTS" {
+				return ""
+			}
+			return fmt.Sprintf("%s=%s\n", strings.ToLower(strings.TrimPrefix(name, variablePrefix)), value)
+		})
+		if err != nil {
+			return err
+		}
+		out = fmt.Sprintf("tests=%s\n", tests) + vars
+	case JUnitOutputFormat:
+		b, err := xml.MarshalIndent(s.junit(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JUnit report: %w", err)
+		}
+		out = xml.Header + string(b) + "\n"
+	default:
+		return fmt.Errorf("unknown output format %q, supported formats: %s", format, strings.Join([]string{
+			TextOutputFormat, JSONOutputFormat, ShellOutputFormat, MakeOutputFormat, JUnitOutputFormat, GitHubOutputFormat,
+		}, ", "))
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// isSelected returns whether the given test runs, every test runs when "All" is selected and
+// hasn't been expanded, otherwise only the selected tests run since the budget might have dropped
+// some of the expanded tests.
+func (s *Selection) isSelected(selected sets.String, test string) bool {
+	return selected.Has(all) || selected.Has(test)
+}
+
+// variables formats the list of selected tests, the All flag and a boolean per known test, it
+// returns an error when different tests map to the same variable, for example, test-e2e and
+// test_e2e.
+func (s *Selection) variables(format func(name, value string) string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(format(variablePrefix+"TESTS", strings.Join(s.Tests, " ")))
+	sb.WriteString(format(variablePrefix+"ALL", fmt.Sprint(s.All)))
+
+	selected := sets.NewString(s.Tests...)
+	// owners maps variable names to what they are used for.
+	owners := map[string]string{
+		variablePrefix + "TESTS": "the selected tests",
+		variablePrefix + "ALL":   "the All flag",
+	}
+	for _, test := range s.knownTests() {
+		name := variableName(test)
+		if owner, ok := owners[name]; ok {
+			return "", fmt.Errorf("test %q maps to variable %s, which is already used by %s", test, name, owner)
+		}
+		owners[name] = fmt.Sprintf("test %q", test)
+		sb.WriteString(format(name, fmt.Sprint(s.isSelected(selected, test))))
+	}
+	return sb.String(), nil
+}
+
+func (s *Selection) junit() *api.TestSuites {
+	suite := &api.TestSuite{Name: "testselect"}
+	selected := sets.NewString(s.Tests...)
+	for _, test := range s.knownTests() {
+		tc := &api.TestCase{Name: test}
+		if !s.isSelected(selected, test) {
+			tc.SkipMessage = &api.SkipMessage{Message: "not selected"}
+		}
+		suite.AddTestCase(tc)
+	}
+	return &api.TestSuites{Suites: []*api.TestSuite{suite}}
+}
+
+// variableName returns the variable name for the given test, for example, TESTSELECT_TEST_E2E for
+// test-e2e.
+func variableName(test string) string {
+	return variablePrefix + nonVariableCharRegex.ReplaceAllString(strings.ToUpper(test), "_")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
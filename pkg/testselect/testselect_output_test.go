@@ -0,0 +1,276 @@
+package testselect
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSelectionWrite(t *testing.T) {
+	ts := TestSuites{
+		List: []TestSuite{
+			{
+				Name:  "Run Always",
+				Tests: []string{"test-unit"},
+			},
+			{
+				Name:         "Run Eventing",
+				RunIfChanged: []string{"^eventing/"},
+				Tests:        []string{"test-e2e"},
+			},
+			{
+				Name:         "Run Kafka",
+				RunIfChanged: []string{"^kafka/"},
+				Tests:        []string{"test-kafka's-e2e"},
+			},
+		},
+	}
+
+	selected, err := Explain(ts, []string{"eventing/broker.go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := Explain(ts, []string{"hack/lib.sh"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		explanation *Explanation
+		expandAll   bool
+		format      string
+		want        string
+	}{
+		{
+			name:        "text",
+			explanation: selected,
+			format:      TextOutputFormat,
+			want:        "test-e2e\ntest-unit\n",
+		},
+		{
+			name:        "text All",
+			explanation: unknown,
+			format:      TextOutputFormat,
+			want:        "All\ntest-unit\n",
+		},
+		{
+			name:        "text expand All",
+			explanation: unknown,
+			expandAll:   true,
+			format:      TextOutputFormat,
+			want:        "test-e2e\ntest-kafka's-e2e\ntest-unit\n",
+		},
+		{
+			name:        "shell",
+			explanation: selected,
+			format:      ShellOutputFormat,
+			want: `TESTSELECT_TESTS='test-e2e test-unit'
+TESTSELECT_ALL='false'
+TESTSELECT_TEST_E2E='true'
+TESTSELECT_TEST_KAFKA_S_E2E='false'
+TESTSELECT_TEST_UNIT='true'
+`,
+		},
+		{
+			name:        "shell expand All",
+			explanation: unknown,
+			expandAll:   true,
+			format:      ShellOutputFormat,
+			want: `TESTSELECT_TESTS='test-e2e test-kafka'\''s-e2e test-unit'
+TESTSELECT_ALL='true'
+TESTSELECT_TEST_E2E='true'
+TESTSELECT_TEST_KAFKA_S_E2E='true'
+TESTSELECT_TEST_UNIT='true'
+`,
+		},
+		{
+			name:        "shell All",
+			explanation: unknown,
+			format:      ShellOutputFormat,
+			want: `TESTSELECT_TESTS='All test-unit'
+TESTSELECT_ALL='true'
+TESTSELECT_TEST_E2E='true'
+TESTSELECT_TEST_KAFKA_S_E2E='true'
+TESTSELECT_TEST_UNIT='true'
+`,
+		},
+		{
+			name:        "make All",
+			explanation: unknown,
+			format:      MakeOutputFormat,
+			want: `TESTSELECT_TESTS := All test-unit
+TESTSELECT_ALL := true
+TESTSELECT_TEST_E2E := true
+TESTSELECT_TEST_KAFKA_S_E2E := true
+TESTSELECT_TEST_UNIT := true
+`,
+		},
+		{
+			name:        "github All",
+			explanation: unknown,
+			format:      GitHubOutputFormat,
+			want: `tests=["All","test-unit"]
+all=true
+test_e2e=true
+test_kafka_s_e2e=true
+test_unit=true
+`,
+		},
+		{
+			name:        "make",
+			explanation: selected,
+			format:      MakeOutputFormat,
+			want: `TESTSELECT_TESTS := test-e2e test-unit
+TESTSELECT_ALL := false
+TESTSELECT_TEST_E2E := true
+TESTSELECT_TEST_KAFKA_S_E2E := false
+TESTSELECT_TEST_UNIT := true
+`,
+		},
+		{
+			name:        "github",
+			explanation: selected,
+			format:      GitHubOutputFormat,
+			want: `tests=["test-e2e","test-unit"]
+all=false
+test_e2e=true
+test_kafka_s_e2e=false
+test_unit=true
+`,
+		},
+		{
+			name:        "json",
+			explanation: selected,
+			format:      JSONOutputFormat,
+			want: `{
+  "tests": [
+    "test-e2e",
+    "test-unit"
+  ],
+  "all": false,
+  "suites": [
+    {
+      "name": "Run Always",
+      "tests": [
+        "test-unit"
+      ],
+      "selected": true
+    },
+    {
+      "name": "Run Eventing",
+      "tests": [
+        "test-e2e"
+      ],
+      "selected": true
+    },
+    {
+      "name": "Run Kafka",
+      "tests": [
+        "test-kafka's-e2e"
+      ],
+      "selected": false
+    }
+  ]
+}
+`,
+		},
+		{
+			name:        "junit",
+			explanation: selected,
+			format:      JUnitOutputFormat,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testselect" tests="3" skipped="1" failures="0" time="0">
+    <testcase name="test-e2e" time="0"></testcase>
+    <testcase name="test-kafka&#39;s-e2e" time="0">
+      <skipped message="not selected"></skipped>
+    </testcase>
+    <testcase name="test-unit" time="0"></testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+		{
+			name:        "junit All",
+			explanation: unknown,
+			format:      JUnitOutputFormat,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testselect" tests="3" skipped="0" failures="0" time="0">
+    <testcase name="test-e2e" time="0"></testcase>
+    <testcase name="test-kafka&#39;s-e2e" time="0"></testcase>
+    <testcase name="test-unit" time="0"></testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := NewSelection(ts, tt.explanation, tt.expandAll).Write(&out, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("Unexpected output (-want, +got): \n%s", diff)
+			}
+		})
+	}
+
+	if err := NewSelection(ts, selected, false).Write(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestSelectionWriteVariableCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		tests   []string
+		wantErr string
+	}{
+		{
+			name:    "dash and underscore",
+			tests:   []string{"test-e2e", "test_e2e"},
+			wantErr: `test "test_e2e" maps to variable TESTSELECT_TEST_E2E, which is already used by test "test-e2e"`,
+		},
+		{
+			name:    "case",
+			tests:   []string{"test-e2e", "TEST-E2E"},
+			wantErr: `test "test-e2e" maps to variable TESTSELECT_TEST_E2E, which is already used by test "TEST-E2E"`,
+		},
+		{
+			name:    "reserved variable",
+			tests:   []string{"tests"},
+			wantErr: `test "tests" maps to variable TESTSELECT_TESTS, which is already used by the selected tests`,
+		},
+		{
+			name:  "no collision",
+			tests: []string{"test-e2e", "test-e2e-tls"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := TestSuites{List: []TestSuite{{Name: "Run Always", Tests: tt.tests}}}
+			e, err := Explain(ts, []string{"eventing/broker.go"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, format := range []string{ShellOutputFormat, MakeOutputFormat, GitHubOutputFormat} {
+				err := NewSelection(ts, e, false).Write(&bytes.Buffer{}, format)
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("%s: unexpected error %v", format, err)
+					}
+					continue
+				}
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("%s: want error %q, got %v", format, tt.wantErr, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package testselect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const (
+	runIfChangedField         = "run_if_changed"
+	runIfChangedPatternsField = "run_if_changed_patterns"
+	skipIfOnlyChangedField    = "skip_if_only_changed"
+	ignoreField               = "ignore"
+)
+
+// suiteMatcher matches changed paths to the conditions of a test suite.
+type suiteMatcher struct {
+	suite TestSuite
+
+	runIfChanged         []*regexp.Regexp
+	runIfChangedPatterns patterns
+	skipIfOnlyChanged    patterns
+}
+
+func newSuiteMatcher(suite TestSuite) (*suiteMatcher, error) {
+	m := &suiteMatcher{
+		suite:                suite,
+		runIfChanged:         make([]*regexp.Regexp, 0, len(suite.RunIfChanged)),
+		runIfChangedPatterns: newPatterns(suite.RunIfChangedPatterns),
+		skipIfOnlyChanged:    newPatterns(suite.SkipIfOnlyChanged),
+	}
+	for _, pathRegex := range suite.RunIfChanged {
+		r, err := regexp.Compile(pathRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid run_if_changed expression %q in suite %q: %w", pathRegex, suite.Name, err)
+		}
+		m.runIfChanged = append(m.runIfChanged, r)
+	}
+	return m, nil
+}
+
+// conditional returns true when the suite runs only for some changed paths, otherwise it always
+// runs.
+func (m *suiteMatcher) conditional() bool {
+	return m.hasRunConditions() || len(m.skipIfOnlyChanged) > 0
+}
+
+func (m *suiteMatcher) hasRunConditions() bool {
+	return len(m.runIfChanged) > 0 || len(m.runIfChangedPatterns) > 0 || len(m.suite.Packages) > 0
+}
+
+// triggeredBy returns the suite field and expression matching the given path when the path
+// triggers the suite, affected are the Go packages affected by the path.
+func (m *suiteMatcher) triggeredBy(path string, affected []string) (field string, expression string, ok bool) {
+	if !m.hasRunConditions() {
+		if len(m.skipIfOnlyChanged) == 0 || m.skippable(path) {
+			return "", "", false
+		}
+		return skipIfOnlyChangedField, "", true
+	}
+	for _, r := range m.runIfChanged {
+		if r.MatchString(path) {
+			return runIfChangedField, r.String(), true
+		}
+	}
+	if p, ok := m.runIfChangedPatterns.match(path); ok {
+		return runIfChangedPatternsField, p, true
+	}
+	if p, importPath, ok := matchPackages(m.suite.Packages, affected); ok {
+		return packagesField, p + " (" + importPath + ")", true
+	}
+	return "", "", false
+}
+
+// skippable returns true when the given path matches skip_if_only_changed.
+func (m *suiteMatcher) skippable(path string) bool {
+	_, ok := m.skipIfOnlyChanged.match(path)
+	return ok
+}
+
+// skipped returns true when every given path matches skip_if_only_changed.
+func (m *suiteMatcher) skipped(paths []string) bool {
+	if len(m.skipIfOnlyChanged) == 0 {
+		return false
+	}
+	for _, path := range paths {
+		if !m.skippable(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// patterns are gitignore-style patterns.
+type patterns []pattern
+
+type pattern struct {
+	raw     string
+	pattern gitignore.Pattern
+}
+
+func newPatterns(raw []string) patterns {
+	ps := make(patterns, 0, len(raw))
+	for _, p := range raw {
+		ps = append(ps, pattern{raw: p, pattern: gitignore.ParsePattern(p, nil)})
+	}
+	return ps
+}
+
+// match returns the pattern deciding whether the given path matches, the last matching pattern
+// wins, so negated patterns exclude paths matched by previous patterns.
+func (ps patterns) match(path string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(ps) - 1; i >= 0; i-- {
+		switch ps[i].pattern.Match(parts, false) {
+		case gitignore.Exclude:
+			return ps[i].raw, true
+		case gitignore.Include:
+			return ps[i].raw, false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,165 @@
+package testselect
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSelectTestsuitesPatterns(t *testing.T) {
+	ts := TestSuites{
+		Ignore: []string{
+			"docs/",
+			"OWNERS",
+			"*.md",
+			"!openshift/**/*.md",
+		},
+		List: []TestSuite{
+			{
+				Name: "Run Eventing Kafka",
+				RunIfChangedPatterns: []string{
+					"/knative-operator/pkg/**/knativekafka/",
+					"!*_test.go",
+				},
+				Tests: []string{
+					"serverless_operator_kafka_e2e_tests",
+				},
+			},
+			{
+				Name: "Run Serving",
+				RunIfChanged: []string{
+					"^serving/",
+				},
+				SkipIfOnlyChanged: []string{
+					"serving/**/*.yaml",
+				},
+				Tests: []string{
+					"serving_e2e_tests",
+				},
+			},
+			{
+				Name: "Run Upgrade",
+				SkipIfOnlyChanged: []string{
+					"test/",
+				},
+				Tests: []string{
+					"upgrade_tests",
+				},
+			},
+			{
+				Name: "Run unit",
+				RunIfChangedPatterns: []string{
+					"*_test.go",
+				},
+				Tests: []string{
+					"unit_tests",
+				},
+			},
+		},
+	}
+
+	tests := []test{
+		{
+			name: "Ignored paths",
+			paths: []string{
+				"docs/mesh.md",
+				"README.md",
+				"knative-operator/OWNERS",
+			},
+			selectedTests: []string{},
+		},
+		{
+			name: "Negated ignore pattern",
+			paths: []string{
+				"openshift/release/README.md",
+			},
+			selectedTests: []string{
+				"upgrade_tests",
+			},
+		},
+		{
+			name: "Glob pattern",
+			paths: []string{
+				"knative-operator/pkg/webhook/knativekafka/webhook_validating.go",
+			},
+			selectedTests: []string{
+				"serverless_operator_kafka_e2e_tests",
+				"upgrade_tests",
+			},
+		},
+		{
+			name: "Negated pattern",
+			paths: []string{
+				"knative-operator/pkg/webhook/knativekafka/webhook_validating_test.go",
+			},
+			selectedTests: []string{
+				"unit_tests",
+				"upgrade_tests",
+			},
+		},
+		{
+			name: "Skip if only changed",
+			paths: []string{
+				"serving/config/deployment.yaml",
+			},
+			selectedTests: []string{
+				"upgrade_tests",
+			},
+		},
+		{
+			name: "Skip if only changed without run if changed",
+			paths: []string{
+				"test/upgrade/upgrade.go",
+			},
+			selectedTests: []string{},
+		},
+		{
+			name: "Not only skippable paths changed",
+			paths: []string{
+				"serving/config/deployment.yaml",
+				"serving/pkg/reconciler.go",
+			},
+			selectedTests: []string{
+				"serving_e2e_tests",
+				"upgrade_tests",
+			},
+		},
+		{
+			// Suites with only skip_if_only_changed are triggered by every other path.
+			name: "Path triggering skip if only changed suite",
+			paths: []string{
+				"hack/lib/serverless.bash",
+			},
+			selectedTests: []string{
+				"upgrade_tests",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filterTests(ts, tt.paths)
+			if err != nil {
+				t.Error(err)
+			}
+			diff := cmp.Diff(tt.selectedTests, result)
+			if diff != "" {
+				t.Errorf("Unexpected tests (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSelectTestsuitesInvalidRegex(t *testing.T) {
+	ts := TestSuites{
+		List: []TestSuite{
+			{
+				Name:         "Invalid",
+				RunIfChanged: []string{"^pkg/(reconciler"},
+			},
+		},
+	}
+	if _, err := filterTests(ts, []string{"pkg/reconciler/broker.go"}); err == nil {
+		t.Fatal("expected error for invalid regular expression")
+	}
+}
@@ -0,0 +1,149 @@
+package testselect
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/openshift-knative/hack/pkg/prowgen"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// SelectRefs returns the refs of the repository under test among the given refs, which include
+// the job refs and the extra refs.
+//
+// When repo (<org>/<repo>) is empty, it returns the refs marked as working directory, or the
+// first refs. It returns nil when there are no refs.
+func SelectRefs(gitRefs []prowapi.Refs, repo string) (*prowapi.Refs, error) {
+	if len(gitRefs) == 0 {
+		return nil, nil
+	}
+	if repo == "" {
+		for i := range gitRefs {
+			if gitRefs[i].WorkDir {
+				return &gitRefs[i], nil
+			}
+		}
+		return &gitRefs[0], nil
+	}
+	for i := range gitRefs {
+		if gitRefs[i].Org+"/"+gitRefs[i].Repo == repo {
+			return &gitRefs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("repository %s not found in clone refs %v", repo, gitRefs)
+}
+
+// DiffRefs returns the paths changed by the given refs:
+//   - for presubmits and batch jobs, it returns the paths changed by the merge of every pull
+//     into the base SHA,
+//   - for postsubmits, which have no pulls, it returns the paths changed between previousBaseSha
+//     and the base SHA, previousBaseSha defaults to the first parent of the base SHA.
+//
+// It returns false when the refs don't include the required SHAs, for example, for periodics.
+func DiffRefs(ctx context.Context, refs prowapi.Refs, previousBaseSha string) ([]string, bool, error) {
+	if refs.BaseSHA == "" {
+		return nil, false, nil
+	}
+	repo := prowgen.Repository{
+		Org:  refs.Org,
+		Repo: refs.Repo,
+	}
+
+	if len(refs.Pulls) == 0 {
+		if previousBaseSha == "" {
+			previousBaseSha = refs.BaseSHA + "^1"
+		}
+		log.Println(repo.RepositoryDirectory(), "Comparing postsubmit base SHA", refs.BaseSHA, "to", previousBaseSha)
+		paths, err := DiffBase(ctx, repo, previousBaseSha, refs.BaseSHA)
+		return paths, true, err
+	}
+
+	pulls := make([]string, 0, len(refs.Pulls))
+	for _, pull := range refs.Pulls {
+		pulls = append(pulls, pullRef(pull))
+	}
+	log.Println(repo.RepositoryDirectory(), "Merging pulls", strings.Join(pulls, ", "), "into base SHA", refs.BaseSHA)
+	paths, err := Diff(ctx, repo, refs.BaseSHA, pulls...)
+	return paths, true, err
+}
+
+// pullRef returns the ref to fetch for the given pull, the pull SHA when it is known, otherwise
+// the pull ref, defaulting to the GitHub pull head ref.
+func pullRef(pull prowapi.Pull) string {
+	if pull.SHA != "" {
+		return pull.SHA
+	}
+	if pull.Ref != "" {
+		return pull.Ref
+	}
+	return fmt.Sprintf("pull/%d/head", pull.Number)
+}
+
+// DiffBase returns the paths changed between from and to.
+func DiffBase(ctx context.Context, repo prowgen.Repository, from, to string) ([]string, error) {
+	if err := prowgen.GitClone(ctx, repo); err != nil {
+		return nil, err
+	}
+	if err := prowgen.GitFetch(ctx, repo, to); err != nil {
+		return nil, err
+	}
+	if err := prowgen.GitCheckout(ctx, repo, to); err != nil {
+		return nil, err
+	}
+	return prowgen.GitDiffNameOnly(ctx, repo, from)
+}
+
+// LocalBase returns the ref the existing checkout of the given refs is compared to:
+//   - for presubmits and batch jobs, clonerefs merges every pull into the base SHA, which is the base,
+//   - for postsubmits, it returns previousBaseSha, defaulting to the first parent of the base SHA.
+//
+// It returns an empty string when the refs don't include the base SHA.
+func LocalBase(refs prowapi.Refs, previousBaseSha string) string {
+	if refs.BaseSHA == "" {
+		return ""
+	}
+	if len(refs.Pulls) > 0 {
+		return refs.BaseSHA
+	}
+	if previousBaseSha != "" {
+		return previousBaseSha
+	}
+	return refs.BaseSHA + "^1"
+}
+
+// LocalDiff returns the paths changed in head since its merge-base with base in the existing
+// checkout dir, it doesn't access the network nor create merge commits.
+func LocalDiff(ctx context.Context, dir, base, head string) ([]string, error) {
+	mergeBase, err := localGit(ctx, dir, "merge-base", base, head)
+	if err != nil {
+		return nil, err
+	}
+	mergeBase = strings.TrimSpace(mergeBase)
+	log.Println(dir, "Comparing", head, "to", base, "merge-base", mergeBase)
+
+	out, err := localGit(ctx, dir, "diff", "--name-only", mergeBase, head)
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+func localGit(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := prowgen.DefaultRunner.Run(ctx, prowgen.Command{
+		Name:   "git",
+		Args:   args,
+		Dir:    dir,
+		Prefix: dir,
+		Quiet:  true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("[%s] %w", dir, err)
+	}
+	return string(out), nil
+}
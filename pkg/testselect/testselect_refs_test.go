@@ -0,0 +1,205 @@
+package testselect
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestSelectRefs(t *testing.T) {
+	serving := prowapi.Refs{Org: "openshift-knative", Repo: "serving", BaseRef: "main"}
+	eventing := prowapi.Refs{Org: "openshift-knative", Repo: "eventing", BaseRef: "main"}
+	eventingWorkDir := eventing
+	eventingWorkDir.WorkDir = true
+
+	tests := []struct {
+		name    string
+		gitRefs []prowapi.Refs
+		repo    string
+		want    *prowapi.Refs
+		wantErr bool
+	}{
+		{
+			name: "no refs",
+		},
+		{
+			name:    "first refs",
+			gitRefs: []prowapi.Refs{serving, eventing},
+			want:    &serving,
+		},
+		{
+			name:    "working directory extra refs",
+			gitRefs: []prowapi.Refs{serving, eventingWorkDir},
+			want:    &eventingWorkDir,
+		},
+		{
+			name:    "repository extra refs",
+			gitRefs: []prowapi.Refs{serving, eventing},
+			repo:    "openshift-knative/eventing",
+			want:    &eventing,
+		},
+		{
+			name:    "unknown repository",
+			gitRefs: []prowapi.Refs{serving, eventing},
+			repo:    "openshift-knative/client",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectRefs(tt.gitRefs, tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Unexpected refs (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullRef(t *testing.T) {
+	tests := []struct {
+		name string
+		pull prowapi.Pull
+		want string
+	}{
+		{
+			name: "sha",
+			pull: prowapi.Pull{Number: 1, SHA: "abcdef", Ref: "refs/pull/1/head"},
+			want: "abcdef",
+		},
+		{
+			name: "ref",
+			pull: prowapi.Pull{Number: 1, Ref: "refs/changes/1"},
+			want: "refs/changes/1",
+		},
+		{
+			name: "number",
+			pull: prowapi.Pull{Number: 1},
+			want: "pull/1/head",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pullRef(tt.pull); got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffRefsWithoutBaseSHA(t *testing.T) {
+	// Periodics extra refs only have a base ref, all tests have to run.
+	_, ok, err := DiffRefs(context.Background(), prowapi.Refs{Org: "openshift-knative", Repo: "eventing", BaseRef: "main"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected refs without base SHA to be ignored")
+	}
+}
+
+func TestLocalBase(t *testing.T) {
+	tests := []struct {
+		name            string
+		refs            prowapi.Refs
+		previousBaseSha string
+		want            string
+	}{
+		{
+			name: "no base SHA",
+			refs: prowapi.Refs{BaseRef: "main"},
+		},
+		{
+			name: "presubmit",
+			refs: prowapi.Refs{BaseSHA: "base", Pulls: []prowapi.Pull{{Number: 1, SHA: "pull"}}},
+			want: "base",
+		},
+		{
+			name: "postsubmit",
+			refs: prowapi.Refs{BaseSHA: "base"},
+			want: "base^1",
+		},
+		{
+			name:            "postsubmit with previous base SHA",
+			refs:            prowapi.Refs{BaseSHA: "base"},
+			previousBaseSha: "previous",
+			want:            "previous",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LocalBase(tt.refs, tt.previousBaseSha); got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalDiff(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write("README.md")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	git("checkout", "-q", "-b", "feature")
+	write("pkg/reconciler/broker.go")
+	git("add", ".")
+	git("commit", "-q", "-m", "feature")
+
+	// Changes on the base branch after the feature branch has been created are not part of the diff.
+	git("checkout", "-q", "main")
+	write("docs/README.md")
+	git("add", ".")
+	git("commit", "-q", "-m", "main")
+
+	got, err := LocalDiff(ctx, dir, "main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"pkg/reconciler/broker.go"}, got); diff != "" {
+		t.Errorf("Unexpected paths (-want, +got): \n%s", diff)
+	}
+
+	got, err = LocalDiff(ctx, dir, "main", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no paths, got %v", got)
+	}
+}
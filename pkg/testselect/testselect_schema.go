@@ -0,0 +1,130 @@
+package testselect
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaPath is the path of the published JSON Schema of the test suites file, relative to the
+// repository root.
+const SchemaPath = "schemas/testsuites.schema.json"
+
+// SchemaMain runs `testselect schema`, which prints the JSON Schema of the test suites file, and
+// returns the exit code.
+func SchemaMain() int {
+	schema, err := JSONSchema()
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	if _, err := os.Stdout.Write(schema); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// JSONSchema returns the JSON Schema of the test suites file generated from the TestSuites type.
+//
+// Constraints are declared with the jsonschema struct tag, for example,
+// `jsonschema:"required,minLength=1,format=regex"` or `jsonschema:"minimum=0"`, string constraints
+// of a list apply to its items.
+func JSONSchema() ([]byte, error) {
+	schema, err := typeSchema(reflect.TypeOf(TestSuites{}), nil)
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "testselect test suites"
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON Schema: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+func typeSchema(t reflect.Type, constraints map[string]string) (map[string]interface{}, error) {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{}, t.NumField())
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fieldConstraints := parseSchemaTag(f.Tag.Get("jsonschema"))
+			if _, ok := fieldConstraints["required"]; ok {
+				required = append(required, name)
+			}
+			property, err := typeSchema(f.Type, fieldConstraints)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+			properties[name] = property
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema, nil
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem(), constraints)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.String:
+		schema := map[string]interface{}{"type": "string"}
+		if v, ok := constraints["minLength"]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid minLength %q: %w", v, err)
+			}
+			schema["minLength"] = n
+		}
+		if v, ok := constraints["format"]; ok {
+			schema["format"] = v
+		}
+		return schema, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		schema := map[string]interface{}{"type": "number"}
+		if v, ok := constraints["minimum"]; ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid minimum %q: %w", v, err)
+			}
+			schema["minimum"] = n
+		}
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// parseSchemaTag parses a jsonschema struct tag, such as "required,minLength=1".
+func parseSchemaTag(tag string) map[string]string {
+	constraints := make(map[string]string)
+	for _, c := range strings.Split(tag, ",") {
+		if c == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(c, "=")
+		constraints[k] = v
+	}
+	return constraints
+}
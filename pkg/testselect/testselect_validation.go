@@ -0,0 +1,94 @@
+package testselect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Validate verifies the test suites upfront, so that errors aren't detected only when a changed
+// path reaches them:
+// - suite names are set and unique,
+// - suites have tests or paths,
+// - regular expressions compile,
+// - patterns, packages and tests are not empty,
+// - cost and risk are not negative.
+func (ts TestSuites) Validate() error {
+	var errs []string
+
+	for i, p := range ts.Ignore {
+		if strings.TrimSpace(p) == "" {
+			errs = append(errs, fmt.Sprintf("ignore[%d]: empty pattern", i))
+		}
+	}
+
+	names := make(map[string]int, len(ts.List))
+	for i, suite := range ts.List {
+		prefix := fmt.Sprintf("testsuites[%d]", i)
+		if suite.Name == "" {
+			errs = append(errs, prefix+": name is required")
+		} else {
+			prefix = fmt.Sprintf("%s %q", prefix, suite.Name)
+			if other, ok := names[suite.Name]; ok {
+				errs = append(errs, fmt.Sprintf("%s: duplicate name, already used by testsuites[%d]", prefix, other))
+			} else {
+				names[suite.Name] = i
+			}
+		}
+
+		hasPaths := len(suite.RunIfChanged) > 0 || len(suite.RunIfChangedPatterns) > 0 ||
+			len(suite.SkipIfOnlyChanged) > 0 || len(suite.Packages) > 0
+		if len(suite.Tests) == 0 && !hasPaths {
+			errs = append(errs, prefix+": suite has neither tests nor paths")
+		}
+
+		errs = append(errs, validateRegexps(prefix, runIfChangedField, suite.RunIfChanged)...)
+		errs = append(errs, validateRegexps(prefix, junitField, suite.JUnit)...)
+		errs = append(errs, validateNotEmpty(prefix, runIfChangedPatternsField, suite.RunIfChangedPatterns)...)
+		errs = append(errs, validateNotEmpty(prefix, skipIfOnlyChangedField, suite.SkipIfOnlyChanged)...)
+		errs = append(errs, validateNotEmpty(prefix, packagesField, suite.Packages)...)
+		errs = append(errs, validateNotEmpty(prefix, "tests", suite.Tests)...)
+
+		if suite.Cost < 0 {
+			errs = append(errs, fmt.Sprintf("%s: cost %v: must not be negative", prefix, suite.Cost))
+		}
+		if suite.Risk < 0 {
+			errs = append(errs, fmt.Sprintf("%s: risk %v: must not be negative", prefix, suite.Risk))
+		}
+
+		for j, p := range suite.Packages {
+			if strings.Contains(strings.TrimSuffix(p, "/..."), "...") {
+				errs = append(errs, fmt.Sprintf("%s: %s[%d] %q: ... is only supported as /... suffix", prefix, packagesField, j, p))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid test suites:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func validateRegexps(prefix string, field string, values []string) []string {
+	var errs []string
+	for i, r := range values {
+		if r == "" {
+			errs = append(errs, fmt.Sprintf("%s: %s[%d]: empty regular expression", prefix, field, i))
+			continue
+		}
+		if _, err := regexp.Compile(r); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s[%d] %q: invalid regular expression: %v", prefix, field, i, r, err))
+		}
+	}
+	return errs
+}
+
+func validateNotEmpty(prefix string, field string, values []string) []string {
+	var errs []string
+	for i, v := range values {
+		if strings.TrimSpace(v) == "" {
+			errs = append(errs, fmt.Sprintf("%s: %s[%d]: empty value", prefix, field, i))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,82 @@
+package testselect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ts      TestSuites
+		wantErr string
+	}{
+		{
+			name: "valid",
+			ts: TestSuites{
+				Ignore: []string{"docs/"},
+				List: []TestSuite{
+					{Name: "Run Always", Tests: []string{"test-unit"}},
+					{Name: "Run nothing", RunIfChanged: []string{"^hack/"}},
+					{Name: "Run Eventing", Packages: []string{"knative.dev/eventing/pkg/..."}, Tests: []string{"test-e2e"}},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			ts: TestSuites{
+				Ignore: []string{""},
+				List: []TestSuite{
+					{Tests: []string{"test-unit"}},
+					{Name: "Run Eventing", RunIfChanged: []string{"^pkg/(", ""}, Tests: []string{"test-e2e"}},
+					{Name: "Run Eventing", Packages: []string{"knative.dev/.../pkg"}, Tests: []string{""}},
+					{Name: "Empty"},
+					{Name: "Negative", Tests: []string{"test-e2e"}, Cost: -1, Risk: -0.5},
+					{Name: "JUnit", Tests: []string{"test-e2e"}, JUnit: []string{"^Test(Broker"}},
+				},
+			},
+			wantErr: `invalid test suites:
+ignore[0]: empty pattern
+testsuites[0]: name is required
+testsuites[1] "Run Eventing": run_if_changed[0] "^pkg/(": invalid regular expression: error parsing regexp: missing closing ): ` + "`^pkg/(`" + `
+testsuites[1] "Run Eventing": run_if_changed[1]: empty regular expression
+testsuites[2] "Run Eventing": duplicate name, already used by testsuites[1]
+testsuites[2] "Run Eventing": tests[0]: empty value
+testsuites[2] "Run Eventing": packages[0] "knative.dev/.../pkg": ... is only supported as /... suffix
+testsuites[3] "Empty": suite has neither tests nor paths
+testsuites[4] "Negative": cost -1: must not be negative
+testsuites[4] "Negative": risk -0.5: must not be negative
+testsuites[5] "JUnit": junit[0] "^Test(Broker": invalid regular expression: error parsing regexp: missing closing ): ` + "`^Test(Broker`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ts.Validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(tt.wantErr, got); diff != "" {
+				t.Errorf("Unexpected error (-want, +got): \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaUpToDate(t *testing.T) {
+	schema, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(filepath.Join("..", "..", SchemaPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(published), string(schema)); diff != "" {
+		t.Errorf("%s is out of date, run make generate-schemas (-published, +generated): \n%s", SchemaPath, diff)
+	}
+}